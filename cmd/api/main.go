@@ -32,20 +32,29 @@ func main() {
 		log.Fatal("❌ Failed to run migrations:", err)
 	}
 
+	if err := database.PromoteAdmins(cfg.AdminEmails); err != nil {
+		log.Fatal("❌ Failed to promote admins:", err)
+	}
+
 	if err := database.ConnectRedis(cfg); err != nil {
 		log.Fatal("❌ Failed to connect to Redis:", err)
 	}
 
+	notifier, err := services.NewNotifier(cfg)
+	if err != nil {
+		log.Fatal("❌ Failed to configure notifications:", err)
+	}
+	emailService := services.NewEmailService(cfg, notifier)
+
 	// start scheduler
-	emailService := services.NewEmailService(cfg)
-	cronScheduler, err := scheduler.StartScheduler(emailService)
+	cronScheduler, err := scheduler.StartScheduler(cfg, emailService)
 	if err != nil {
 		log.Fatal("❌ Failed to start scheduler:", err)
 	}
 	defer cronScheduler.Shutdown()
 
 	// Setup routes
-	router := setupRoutes(cfg)
+	router := setupRoutes(cfg, emailService, cronScheduler)
 
 	router.Use(middleware.CORSMiddleware())
 
@@ -5,20 +5,27 @@ import (
 	"github.com/pick-cee/events-api/internal/config"
 	"github.com/pick-cee/events-api/internal/handlers"
 	"github.com/pick-cee/events-api/internal/middleware"
+	"github.com/pick-cee/events-api/internal/models"
+	"github.com/pick-cee/events-api/internal/scheduler"
 	"github.com/pick-cee/events-api/internal/services"
 )
 
-func setupRoutes(cfg *config.Config) *gin.Engine {
+func setupRoutes(cfg *config.Config, emailService *services.EmailService, jobScheduler *scheduler.Scheduler) *gin.Engine {
 	r := gin.Default()
 	gin.SetMode(gin.ReleaseMode)
 
-	// initialize services needed by handlers
-	emailService := services.NewEmailService(cfg)
-
 	// initialize handlers
 	authHandler := handlers.NewAuthHandler(cfg, emailService)
-	eventHandler := handlers.NewEventHandler()
-	registrationHandler := handlers.NewRegistrationHandler(emailService)
+	eventHandler := handlers.NewEventHandler(cfg, emailService)
+	registrationHandler := handlers.NewRegistrationHandler(cfg, emailService)
+	seriesHandler := handlers.NewSeriesHandler(cfg, emailService)
+	adminHandler := handlers.NewAdminHandler(cfg)
+	collaboratorHandler := handlers.NewCollaboratorHandler(cfg, emailService)
+	invitationHandler := handlers.NewInvitationHandler(cfg, emailService)
+	calendarHandler := handlers.NewCalendarHandler(cfg)
+	outboxHandler := handlers.NewOutboxHandler()
+	notificationHandler := handlers.NewNotificationHandler(cfg)
+	jobHandler := handlers.NewJobHandler(jobScheduler)
 
 	// Health check
 	r.GET("/health", func(c *gin.Context) {
@@ -33,29 +40,133 @@ func setupRoutes(cfg *config.Config) *gin.Engine {
 		{
 			auth.POST("/signup", authHandler.Signup)
 			auth.POST("/login", authHandler.Login)
+			auth.POST("/refresh", authHandler.Refresh)
+			auth.POST("/forgot-password", authHandler.ForgotPassword)
+			auth.POST("/reset-password", authHandler.ResetPassword)
+			auth.POST("/verify-email", authHandler.VerifyEmail)
 		}
 
-		// public event routes
+		// public event routes; signed-in organizers and invitees also see
+		// private events
+		optionalAuth := middleware.OptionalAuth(cfg)
 		events := v1.Group("/events")
 		{
 			events.GET("", eventHandler.ListEvents)
-			events.GET("/:id", eventHandler.GetEventById)
-			events.GET("/:id/attendees", registrationHandler.GetEventAttendees)
+			events.GET("/:id", optionalAuth, eventHandler.GetEventById)
+			events.GET("/:id/attendees", optionalAuth, registrationHandler.GetEventAttendees)
+			events.GET("/:id/questions", optionalAuth, eventHandler.GetRegistrationQuestions)
+			events.GET("/:id/calendar.ics", optionalAuth, calendarHandler.GetEventCalendar)
 		}
 
+		// public event series routes
+		v1.GET("/series/:id", optionalAuth, seriesHandler.GetSeriesById)
+
+		// personal calendar feeds, authenticated by the token in their URL
+		v1.GET("/calendar/feed/:token", calendarHandler.GetCalendarFeed)
+
+		// one-click unsubscribe links in emails, authenticated by their signed
+		// token. Following the link only asks for confirmation
+		v1.GET("/notifications/unsubscribe", notificationHandler.ConfirmUnsubscribe)
+		v1.POST("/notifications/unsubscribe", notificationHandler.Unsubscribe)
+
 		// protected routes
 		protected := v1.Group("")
 		protected.Use(middleware.AuthMidleware(cfg))
+
+		// blocks unverified users when REQUIRE_VERIFIED_EMAIL is set
+		verified := middleware.RequireVerifiedEmail(cfg)
+		canCreateEvents := middleware.RequirePermission(models.PermissionCreateEvents)
 		{
+			// Session management (authenticated users)
+			protected.POST("/auth/logout", authHandler.Logout)                                            // POST /api/v1/auth/logout
+			protected.POST("/auth/verify-email/resend", authHandler.ResendVerificationEmail)              // POST /api/v1/auth/verify-email/resend
+			protected.GET("/auth/me", authHandler.GetProfile)                                             // GET /api/v1/auth/me
+			protected.PATCH("/auth/me", authHandler.UpdateProfile)                                        // PATCH /api/v1/auth/me
+			protected.GET("/notification-preferences", notificationHandler.GetNotificationPreferences)    // GET /api/v1/notification-preferences
+			protected.PUT("/notification-preferences", notificationHandler.UpdateNotificationPreferences) // PUT /api/v1/notification-preferences
+
 			// Event management (authenticated users)
-			protected.POST("/events", eventHandler.CreateEvent)       // POST /api/v1/events
-			protected.PUT("/events/:id", eventHandler.UpdateEvent)    // PUT /api/v1/events/:id
-			protected.DELETE("/events/:id", eventHandler.DeleteEvent) // DELETE /api/v1/events/:id
+			protected.POST("/events", verified, canCreateEvents, eventHandler.CreateEvent)         // POST /api/v1/events
+			protected.PUT("/events/:id", eventHandler.UpdateEvent)                                 // PUT /api/v1/events/:id
+			protected.DELETE("/events/:id", eventHandler.DeleteEvent)                              // DELETE /api/v1/events/:id
+			protected.POST("/events/:id/cancellation", eventHandler.CancelEvent)                   // POST /api/v1/events/:id/cancellation
+			protected.GET("/events/:id/reminders", eventHandler.GetEventReminders)                 // GET /api/v1/events/:id/reminders
+			protected.PUT("/events/:id/reminders", eventHandler.UpdateEventReminders)              // PUT /api/v1/events/:id/reminders
+			protected.GET("/events/:id/reminders/deliveries", eventHandler.ListReminderDeliveries) // GET /api/v1/events/:id/reminders/deliveries
+			protected.PUT("/events/:id/questions", eventHandler.UpdateRegistrationQuestions)       // PUT /api/v1/events/:id/questions
 
 			// Event registration (authenticated users)
-			protected.POST("/events/:id/register", registrationHandler.RegisterForEvent)   // POST /api/v1/events/:id/register
-			protected.DELETE("/events/:id/cancel", registrationHandler.CancelRegistration) // DELETE /api/v1/events/:id/register
-			protected.GET("/my-registrations", registrationHandler.GetMyRegistrations)     // GET /api/v1/my-registrations
+			protected.POST("/events/:id/register", verified, registrationHandler.RegisterForEvent)        // POST /api/v1/events/:id/register
+			protected.DELETE("/events/:id/cancel", registrationHandler.CancelRegistration)                // DELETE /api/v1/events/:id/register
+			protected.GET("/my-registrations", registrationHandler.GetMyRegistrations)                    // GET /api/v1/my-registrations
+			protected.DELETE("/events/:id/attendees/:userId", registrationHandler.RemoveAttendee)         // DELETE /api/v1/events/:id/attendees/:userId
+			protected.GET("/events/:id/registrations", registrationHandler.ListRegistrations)             // GET /api/v1/events/:id/registrations?status=pending
+			protected.GET("/events/:id/attendees/export", registrationHandler.ExportAttendees)            // GET /api/v1/events/:id/attendees/export?format=csv|xlsx|ndjson
+			protected.POST("/events/:id/registrations/approve", registrationHandler.ApproveRegistrations) // POST /api/v1/events/:id/registrations/approve
+			protected.POST("/events/:id/registrations/reject", registrationHandler.RejectRegistrations)   // POST /api/v1/events/:id/registrations/reject
+
+			// Tickets and check-in (authenticated users)
+			protected.GET("/registrations/:id/ticket", registrationHandler.GetTicket)          // GET /api/v1/registrations/:id/ticket
+			protected.GET("/registrations/:id/ticket/qr", registrationHandler.GetTicketQRCode) // GET /api/v1/registrations/:id/ticket/qr?format=png|svg&size=256
+			protected.POST("/events/:id/check-in", registrationHandler.CheckInAttendee)        // POST /api/v1/events/:id/check-in
+			protected.GET("/events/:id/attendance", registrationHandler.GetAttendance)         // GET /api/v1/events/:id/attendance
+
+			// Calendar feed (authenticated users)
+			protected.POST("/calendar/feed", calendarHandler.CreateCalendarFeed)   // POST /api/v1/calendar/feed
+			protected.GET("/calendar/feed", calendarHandler.GetCalendarFeedStatus) // GET /api/v1/calendar/feed
+			protected.DELETE("/calendar/feed", calendarHandler.DeleteCalendarFeed) // DELETE /api/v1/calendar/feed
+
+			// Event co-organizers (authenticated users)
+			protected.POST("/events/:id/collaborators", collaboratorHandler.InviteCollaborator)                   // POST /api/v1/events/:id/collaborators
+			protected.GET("/events/:id/collaborators", collaboratorHandler.ListCollaborators)                     // GET /api/v1/events/:id/collaborators
+			protected.PATCH("/events/:id/collaborators/:collaboratorId", collaboratorHandler.UpdateCollaborator)  // PATCH /api/v1/events/:id/collaborators/:collaboratorId
+			protected.DELETE("/events/:id/collaborators/:collaboratorId", collaboratorHandler.RemoveCollaborator) // DELETE /api/v1/events/:id/collaborators/:collaboratorId
+			protected.GET("/my-collaborations", collaboratorHandler.GetMyCollaborations)                          // GET /api/v1/my-collaborations
+			protected.POST("/collaborations/:id/accept", collaboratorHandler.AcceptInvitation)                    // POST /api/v1/collaborations/:id/accept
+			protected.POST("/collaborations/:id/decline", collaboratorHandler.DeclineInvitation)                  // POST /api/v1/collaborations/:id/decline
+
+			// Private event invitations (authenticated users)
+			protected.POST("/events/:id/invitations", invitationHandler.InviteAttendees)                  // POST /api/v1/events/:id/invitations
+			protected.GET("/events/:id/invitations", invitationHandler.ListInvitations)                   // GET /api/v1/events/:id/invitations
+			protected.DELETE("/events/:id/invitations/:invitationId", invitationHandler.RevokeInvitation) // DELETE /api/v1/events/:id/invitations/:invitationId
+			protected.POST("/event-invitations/accept", invitationHandler.AcceptEventInvitation)          // POST /api/v1/event-invitations/accept
+
+			// Recurring event series (authenticated users)
+			protected.POST("/series", verified, canCreateEvents, seriesHandler.CreateSeries)     // POST /api/v1/series
+			protected.PUT("/series/:id/occurrences/:eventId", seriesHandler.UpdateOccurrence)    // PUT /api/v1/series/:id/occurrences/:eventId?scope=this|following
+			protected.DELETE("/series/:id/occurrences/:eventId", seriesHandler.CancelOccurrence) // DELETE /api/v1/series/:id/occurrences/:eventId?scope=this|following
+			protected.POST("/series/:id/register", verified, seriesHandler.RegisterForSeries)    // POST /api/v1/series/:id/register
+		}
+
+		// admin routes
+		admin := protected.Group("/admin")
+		admin.Use(middleware.RequirePermission(models.PermissionManageUsers))
+		{
+			admin.GET("/users", adminHandler.ListUsers)           // GET /api/v1/admin/users
+			admin.GET("/users/:id", adminHandler.GetUser)         // GET /api/v1/admin/users/:id
+			admin.PATCH("/users/:id", adminHandler.UpdateUser)    // PATCH /api/v1/admin/users/:id
+			admin.DELETE("/users/:id", adminHandler.DeleteUser)   // DELETE /api/v1/admin/users/:id
+			admin.PUT("/events/:id", eventHandler.UpdateEvent)    // PUT /api/v1/admin/events/:id
+			admin.DELETE("/events/:id", eventHandler.DeleteEvent) // DELETE /api/v1/admin/events/:id
+
+			// Notification outbox
+			admin.GET("/outbox", outboxHandler.ListOutboxMessages)               // GET /api/v1/admin/outbox?status=dead
+			admin.POST("/outbox/replay", outboxHandler.ReplayDeadOutboxMessages) // POST /api/v1/admin/outbox/replay
+			admin.GET("/outbox/:id", outboxHandler.GetOutboxMessage)             // GET /api/v1/admin/outbox/:id
+			admin.POST("/outbox/:id/replay", outboxHandler.ReplayOutboxMessage)  // POST /api/v1/admin/outbox/:id/replay
+
+			// Notification templates
+			admin.GET("/notifications/templates", notificationHandler.ListNotificationTemplates) // GET /api/v1/admin/notifications/templates
+			admin.GET("/notifications/:type/preview", notificationHandler.PreviewNotification)   // GET /api/v1/admin/notifications/:type/preview?locale=fr&format=html
+			admin.POST("/notifications/:type/preview", notificationHandler.PreviewNotification)  // POST /api/v1/admin/notifications/:type/preview
+
+			// Scheduled jobs
+			admin.GET("/jobs", jobHandler.ListJobs)                // GET /api/v1/admin/jobs
+			admin.POST("/jobs/:name/run", jobHandler.TriggerJob)   // POST /api/v1/admin/jobs/:name/run
+			admin.POST("/jobs/:name/pause", jobHandler.PauseJob)   // POST /api/v1/admin/jobs/:name/pause
+			admin.POST("/jobs/:name/resume", jobHandler.ResumeJob) // POST /api/v1/admin/jobs/:name/resume
+			admin.GET("/job-runs", jobHandler.ListJobRuns)         // GET /api/v1/admin/job-runs?job=event-reminders&status=failed
+			admin.GET("/job-runs/:id", jobHandler.GetJobRun)       // GET /api/v1/admin/job-runs/:id
 		}
 	}
 	return r
@@ -3,11 +3,16 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/pick-cee/events-api/internal/database"
+	"github.com/redis/go-redis/v9"
 )
 
+// EventListNamespace versions every cached event list page.
+const EventListNamespace = "events:list"
+
 // Set a value in cache
 func Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
 	data, err := json.Marshal(value)
@@ -38,3 +43,73 @@ func Exists(ctx context.Context, key string) (bool, error) {
 	count, err := database.RedisClient.Exists(ctx, key).Result()
 	return count > 0, err
 }
+
+// extendTTL raises a key's time to live to ARGV[1] milliseconds, never
+// lowering it; a key without one gets it too. EXPIRE GT would do the same but
+// needs Redis 7.
+var extendTTL = redis.NewScript(`
+if redis.call("PTTL", KEYS[1]) < tonumber(ARGV[1]) then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return 0
+`)
+
+// SetWithTags stores a value and records its key under each tag so that
+// InvalidateTags can later evict every key sharing a tag.
+func SetWithTags(ctx context.Context, key string, value interface{}, expiration time.Duration, tags ...string) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	_, err = database.RedisClient.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, key, data, expiration)
+		for _, tag := range tags {
+			pipe.SAdd(ctx, tagKey(tag), key)
+			// a tag lives as long as the longest-lived key it points to. The
+			// script is sent in full, as EVALSHA cannot fall back inside MULTI
+			extendTTL.Eval(ctx, pipe, []string{tagKey(tag)}, expiration.Milliseconds())
+		}
+		return nil
+	})
+	return err
+}
+
+// InvalidateTags deletes every key recorded under the given tags.
+func InvalidateTags(ctx context.Context, tags ...string) error {
+	for _, tag := range tags {
+		keys, err := database.RedisClient.SMembers(ctx, tagKey(tag)).Result()
+		if err != nil {
+			return err
+		}
+
+		if err := Delete(ctx, append(keys, tagKey(tag))...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Namespace returns a versioned key prefix such as "events:list:v3". Bumping
+// the namespace makes every key built from the old prefix unreachable at once;
+// the orphaned keys expire on their own.
+func Namespace(ctx context.Context, name string) (string, error) {
+	version, err := database.RedisClient.Get(ctx, versionKey(name)).Int64()
+	if err != nil && err != redis.Nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:v%d", name, version), nil
+}
+
+// BumpNamespace invalidates every key under a versioned namespace.
+func BumpNamespace(ctx context.Context, name string) error {
+	return database.RedisClient.Incr(ctx, versionKey(name)).Err()
+}
+
+func tagKey(tag string) string {
+	return "tag:" + tag
+}
+
+func versionKey(name string) string {
+	return "version:" + name
+}
@@ -3,6 +3,9 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -16,6 +19,50 @@ type Config struct {
 	DBName     string
 	JWTSecret  string
 	RedisURL   string
+
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	// AppURL is the frontend base URL used to build links in emails; APIURL
+	// is this API's public base URL, used for links handled by the API itself
+	AppURL               string
+	APIURL               string
+	RequireVerifiedEmail bool
+
+	// AdminEmails, lower-cased, are promoted to the admin role once their
+	// owner verifies the address
+	AdminEmails []string
+
+	// OutboxPollInterval is how often the outbox worker looks for due
+	// notifications; OutboxMaxAttempts is how often delivery is tried before a
+	// notification is dead-lettered
+	OutboxPollInterval time.Duration
+	OutboxMaxAttempts  int
+
+	// ReminderPollInterval is how often the reminder job looks ahead for
+	// reminders falling due; within an interval each is sent at its due time.
+	// ReminderMaxAttempts is how often a reminder whose delivery failed is
+	// queued again
+	ReminderPollInterval time.Duration
+	ReminderMaxAttempts  int
+
+	// JobRunRetention is how long the history of scheduled job runs is kept
+	JobRunRetention time.Duration
+
+	// NotificationProvider selects how notifications are delivered: novu,
+	// smtp or file
+	NotificationProvider string
+	NovuSecretKey        string
+
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+
+	// NotificationFile is where the file provider appends messages; empty
+	// or "-" writes to stdout
+	NotificationFile string
 }
 
 func Load() *Config {
@@ -32,6 +79,34 @@ func Load() *Config {
 		DBName:     GetEnv("DB_NAME", ""),
 		JWTSecret:  GetEnv("JWT_SECRET", ""),
 		RedisURL:   GetEnv("REDIS_URL", ""),
+
+		AccessTokenTTL:  GetDurationEnv("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL: GetDurationEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+
+		AppURL:               GetEnv("APP_URL", "http://localhost:3000"),
+		APIURL:               GetEnv("API_URL", "http://localhost:8080"),
+		RequireVerifiedEmail: GetBoolEnv("REQUIRE_VERIFIED_EMAIL", false),
+
+		AdminEmails: lowerAll(GetListEnv("ADMIN_EMAILS")),
+
+		OutboxPollInterval: GetDurationEnv("OUTBOX_POLL_INTERVAL", 15*time.Second),
+		OutboxMaxAttempts:  GetIntEnv("OUTBOX_MAX_ATTEMPTS", 8),
+
+		ReminderPollInterval: GetDurationEnv("REMINDER_POLL_INTERVAL", time.Minute),
+		ReminderMaxAttempts:  GetIntEnv("REMINDER_MAX_ATTEMPTS", 3),
+
+		JobRunRetention: GetDurationEnv("JOB_RUN_RETENTION", 14*24*time.Hour),
+
+		NotificationProvider: GetEnv("NOTIFICATION_PROVIDER", "novu"),
+		NovuSecretKey:        GetEnv("NOVU_SECRET_KEY", ""),
+
+		SMTPHost:     GetEnv("SMTP_HOST", ""),
+		SMTPPort:     GetEnv("SMTP_PORT", "587"),
+		SMTPUsername: GetEnv("SMTP_USERNAME", ""),
+		SMTPPassword: GetEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:     GetEnv("SMTP_FROM", ""),
+
+		NotificationFile: GetEnv("NOTIFICATION_FILE", ""),
 	}
 }
 
@@ -41,3 +116,53 @@ func GetEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// GetDurationEnv parses a Go duration such as "15m" or "720h".
+func GetDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration for %s, using %s\n", key, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
+
+func GetIntEnv(key string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func GetBoolEnv(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// lowerAll lower-cases every value in place.
+func lowerAll(values []string) []string {
+	for i, value := range values {
+		values[i] = strings.ToLower(value)
+	}
+	return values
+}
+
+// GetListEnv splits a comma-separated value, dropping empty entries.
+func GetListEnv(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -29,20 +29,103 @@ func Connect(cfg *config.Config) error {
 func Migrate() error {
 	log.Println("🔄 Running migrations...")
 
+	hadRoles := DB.Migrator().HasColumn(&models.User{}, "Role")
+	hadReminders := DB.Migrator().HasTable(&models.EventReminder{})
+	hadReminderLedger := DB.Migrator().HasColumn(&models.ReminderDelivery{}, "Status")
+
 	err := DB.AutoMigrate(
 		&models.User{},
+		&models.EventSeries{},
 		&models.Event{},
 		&models.Registration{},
+		&models.RefreshToken{},
+		&models.UserToken{},
+		&models.EventCollaborator{},
+		&models.EventInvitation{},
+		&models.OutboxMessage{},
+		&models.NotificationPreference{},
+		&models.EventReminder{},
+		&models.RegistrationQuestion{},
+		&models.ReminderDelivery{},
+		&models.JobRun{},
+		&models.JobState{},
+		&models.CalendarFeed{},
 	)
 
 	if err != nil {
 		return err
 	}
 
+	// events created before end times existed default to one hour
+	if err := DB.Exec("UPDATE events SET end_time = date_time + interval '1 hour' WHERE end_time IS NULL").Error; err != nil {
+		return err
+	}
+
+	// when roles are introduced, existing users keep being able to create
+	// events, as organizers
+	if !hadRoles {
+		if err := DB.Exec("UPDATE users SET role = ?", models.RoleOrganizer).Error; err != nil {
+			return err
+		}
+	}
+
+	// when reminder schedules are introduced, upcoming events keep the fixed
+	// 24 hour and one hour reminders they had before. Those already due went
+	// out under the old schedule, so they are recorded as sent rather than
+	// sent again
+	if !hadReminders {
+		for _, offset := range models.DefaultReminderOffsets {
+			if err := DB.Exec("INSERT INTO event_reminders (event_id, offset_minutes, created_at) SELECT id, ?, now() FROM events WHERE date_time > now() AND deleted_at IS NULL", offset).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := DB.Exec(`INSERT INTO reminder_deliveries (registration_id, offset_minutes, event_id, status, attempts, sent_at, created_at, updated_at)
+			SELECT r.id, er.offset_minutes, r.event_id, ?, 1, e.date_time - er.offset_minutes * interval '1 minute', now(), now()
+			FROM registrations r
+			JOIN events e ON e.id = r.event_id
+			JOIN event_reminders er ON er.event_id = e.id
+			WHERE r.deleted_at IS NULL AND e.date_time - er.offset_minutes * interval '1 minute' <= now()
+			ON CONFLICT DO NOTHING`, models.ReminderStatusSent).Error; err != nil {
+			return err
+		}
+	}
+
+	// deliveries recorded before the ledger tracked status were queued once
+	if !hadReminderLedger {
+		if err := DB.Exec("UPDATE reminder_deliveries d SET event_id = r.event_id, attempts = 1 FROM registrations r WHERE r.id = d.registration_id").Error; err != nil {
+			return err
+		}
+	}
+
+	// one-time links were once queued in plaintext; scrub them from messages
+	// that were already sent or gave up
+	for _, key := range []string{"resetUrl", "verifyUrl"} {
+		if err := DB.Exec("UPDATE outbox_messages SET payload = (payload - ?::text) || jsonb_build_object('sealed', jsonb_build_object(?::text, '')) WHERE status IN ('sent', 'dead') AND payload->>? IS NOT NULL", key, key, key).Error; err != nil {
+			return err
+		}
+	}
+
+	// full-text search over title and description, used by ListEvents
+	if err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_events_search ON events USING GIN (to_tsvector('english', coalesce(title, '') || ' ' || coalesce(description, '')))").Error; err != nil {
+		return err
+	}
+
 	log.Println("✅ Migrations completed")
 	return nil
 }
 
+// PromoteAdmins gives the admin role to the users with the configured,
+// lower-cased email addresses, once they verified them. Until then anyone
+// could have signed up with the address.
+func PromoteAdmins(emails []string) error {
+	if len(emails) == 0 {
+		return nil
+	}
+	return DB.Model(&models.User{}).Where("LOWER(email) IN ? AND email_verified_at IS NOT NULL", emails).
+		Update("role", models.RoleAdmin).Error
+}
+
 func Disconnect() error {
 	if DB == nil {
 		return nil
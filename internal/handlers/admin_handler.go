@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pick-cee/events-api/internal/config"
+	"github.com/pick-cee/events-api/internal/database"
+	"github.com/pick-cee/events-api/internal/middleware"
+	"github.com/pick-cee/events-api/internal/models"
+	"github.com/pick-cee/events-api/internal/services"
+	"github.com/pick-cee/events-api/internal/utils"
+)
+
+type AdminHandler struct {
+	cfg *config.Config
+}
+
+func NewAdminHandler(cfg *config.Config) *AdminHandler {
+	return &AdminHandler{
+		cfg: cfg,
+	}
+}
+
+// Request/Response DTOs
+type UpdateUserRequest struct {
+	Name   string `json:"name"`
+	Email  string `json:"email" binding:"omitempty,email"`
+	Role   string `json:"role" binding:"omitempty,oneof=admin organizer attendee"`
+	Locale string `json:"locale"`
+}
+
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	params := utils.GetPaginationParams(c.Request)
+
+	query := database.DB.Model(&models.User{})
+	if role := c.Query("role"); role != "" {
+		query = query.Where("role = ?", role)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to count users")
+		return
+	}
+
+	var users []models.User
+	if err := query.Scopes(utils.Paginate(params)).Order("id ASC").Find(&users).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch users")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, utils.NewPaginationResponse(users, total, params))
+}
+
+func (h *AdminHandler) GetUser(c *gin.Context) {
+	var user models.User
+	if err := database.DB.Preload("Events").First(&user, c.Param("id")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "User not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, user)
+}
+
+// UpdateUser changes a user's details or role. A role change ends the user's
+// sessions so the new role applies to their next token.
+func (h *AdminHandler) UpdateUser(c *gin.Context) {
+	var request UpdateUserRequest
+
+	var user models.User
+	if err := database.DB.First(&user, c.Param("id")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	if user.ID == middleware.GetUserId(c) && request.Role != "" && request.Role != user.Role {
+		utils.ErrorResponse(c, http.StatusForbidden, "You cannot change your own role")
+		return
+	}
+
+	if request.Email != "" && request.Email != user.Email {
+		var existing models.User
+		if err := database.DB.Where("email = ?", request.Email).First(&existing).Error; err == nil {
+			utils.ErrorResponse(c, http.StatusConflict, "Email already registered")
+			return
+		}
+	}
+
+	roleChanged := request.Role != "" && request.Role != user.Role
+
+	updates := map[string]any{}
+	if request.Name != "" {
+		updates["name"] = request.Name
+	}
+	if request.Email != "" {
+		updates["email"] = request.Email
+	}
+	if request.Role != "" {
+		updates["role"] = request.Role
+	}
+	if request.Locale != "" {
+		locale := services.NormalizeLocale(request.Locale)
+		if locale == "" {
+			utils.ValidationErrorResponse(c, "unsupported locale")
+			return
+		}
+		updates["locale"] = locale
+	}
+
+	if err := database.DB.Model(&user).Updates(updates).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update user")
+		return
+	}
+
+	if roleChanged {
+		h.endSessions(c, user.ID)
+	}
+
+	database.DB.First(&user, user.ID)
+
+	utils.SuccessResponse(c, http.StatusOK, user)
+}
+
+func (h *AdminHandler) DeleteUser(c *gin.Context) {
+	var user models.User
+	if err := database.DB.First(&user, c.Param("id")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if user.ID == middleware.GetUserId(c) {
+		utils.ErrorResponse(c, http.StatusForbidden, "You cannot delete your own account")
+		return
+	}
+
+	if err := database.DB.Delete(&user).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete user")
+		return
+	}
+
+	h.endSessions(c, user.ID)
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{"message": "User deleted successfully"})
+}
+
+func (h *AdminHandler) endSessions(c *gin.Context, userID uint) {
+	if err := revokeRefreshTokens(database.DB.Where("user_id = ?", userID)); err != nil {
+		log.Printf("❌ Failed to revoke refresh tokens for user %d: %v\n", userID, err)
+	}
+
+	if err := middleware.RevokeAllForUser(c.Request.Context(), userID, h.cfg.AccessTokenTTL); err != nil {
+		log.Printf("❌ Failed to revoke access tokens for user %d: %v\n", userID, err)
+	}
+}
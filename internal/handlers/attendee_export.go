@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pick-cee/events-api/internal/database"
+	"github.com/pick-cee/events-api/internal/models"
+	"github.com/pick-cee/events-api/internal/utils"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+// exportBatchSize is how many registrations an export loads at a time
+const exportBatchSize = 500
+
+// attendeeExportColumns lead every CSV and XLSX export; one column per
+// question of the registration form follows.
+var attendeeExportColumns = []string{"Registration ID", "Name", "Email", "Status", "Registered At", "Checked In At"}
+
+// attendeeExportRow is a registration as exported, with times in the event's
+// time zone.
+type attendeeExportRow struct {
+	RegistrationID uint                        `json:"registration_id"`
+	Name           string                      `json:"name"`
+	Email          string                      `json:"email"`
+	Status         string                      `json:"status"`
+	RegisteredAt   time.Time                   `json:"registered_at"`
+	CheckedInAt    *time.Time                  `json:"checked_in_at"`
+	Answers        []models.RegistrationAnswer `json:"answers"`
+}
+
+// attendeeExporter writes export rows in one file format. Rows are written
+// as they come, so an export never holds more than a batch in memory.
+type attendeeExporter interface {
+	WriteRow(row *attendeeExportRow) error
+	// Flush sends the rows written so far to the client
+	Flush() error
+	Close() error
+}
+
+// ExportAttendees streams an event's registrations, with their answers and
+// check-in times, as ?format=csv (the default), xlsx or ndjson. ?status=
+// narrows the export like ListRegistrations does.
+func (h *RegistrationHandler) ExportAttendees(c *gin.Context) {
+	var event models.Event
+	if err := database.DB.Preload("Questions", orderByPosition).First(&event, c.Param("id")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	if !canManageEvent(c, &event, models.CollaboratorPermissionAttendees) {
+		utils.ErrorResponse(c, http.StatusForbidden, "You are not allowed to manage attendees of this event")
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	var contentType string
+	switch format {
+	case "csv":
+		contentType = "text/csv; charset=utf-8"
+	case "xlsx":
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case "ndjson":
+		contentType = "application/x-ndjson"
+	default:
+		utils.ValidationErrorResponse(c, "format must be csv, xlsx or ndjson")
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="event-%d-attendees.%s"`, event.ID, format))
+	c.Header("Cache-Control", "private, no-store")
+	c.Status(http.StatusOK)
+
+	var exporter attendeeExporter
+	var err error
+	switch format {
+	case "csv":
+		exporter, err = newCSVExporter(c.Writer, event.Questions)
+	case "xlsx":
+		exporter, err = newXLSXExporter(c.Writer, event.Questions)
+	case "ndjson":
+		exporter = newNDJSONExporter(c.Writer)
+	}
+	if err != nil {
+		log.Printf("❌ Failed to start attendee export of event %d: %v\n", event.ID, err)
+		return
+	}
+
+	query := database.DB.Where("event_id = ?", event.ID).Preload("User")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	location := event.TimeLocation()
+	var batch []models.Registration
+	err = query.FindInBatches(&batch, exportBatchSize, func(tx *gorm.DB, _ int) error {
+		for _, registration := range batch {
+			row := attendeeExportRow{
+				RegistrationID: registration.ID,
+				Name:           registration.User.Name,
+				Email:          registration.User.Email,
+				Status:         registration.Status,
+				RegisteredAt:   registration.CreatedAt.In(location),
+				Answers:        registration.Answers,
+			}
+			if registration.CheckedInAt != nil {
+				checkedIn := registration.CheckedInAt.In(location)
+				row.CheckedInAt = &checkedIn
+			}
+
+			if err := exporter.WriteRow(&row); err != nil {
+				return err
+			}
+		}
+		return exporter.Flush()
+	}).Error
+	if closeErr := exporter.Close(); err == nil {
+		err = closeErr
+	}
+
+	// the response has started, so a failure can only cut the file short
+	if err != nil {
+		log.Printf("❌ Attendee export of event %d failed: %v\n", event.ID, err)
+	}
+}
+
+// csvExporter writes one line per registration, answers in form order.
+type csvExporter struct {
+	writer    *csv.Writer
+	flusher   http.Flusher
+	questions []models.RegistrationQuestion
+}
+
+func newCSVExporter(w gin.ResponseWriter, questions []models.RegistrationQuestion) (*csvExporter, error) {
+	exporter := &csvExporter{writer: csv.NewWriter(w), flusher: w, questions: questions}
+	if err := exporter.writer.Write(exportHeader(questions)); err != nil {
+		return nil, err
+	}
+	return exporter, nil
+}
+
+func (e *csvExporter) WriteRow(row *attendeeExportRow) error {
+	record := []string{
+		strconv.FormatUint(uint64(row.RegistrationID), 10),
+		csvText(row.Name),
+		csvText(row.Email),
+		row.Status,
+		row.RegisteredAt.Format(time.RFC3339),
+		"",
+	}
+	if row.CheckedInAt != nil {
+		record[5] = row.CheckedInAt.Format(time.RFC3339)
+	}
+
+	for _, value := range exportAnswers(e.questions, row.Answers) {
+		switch value.(type) {
+		case float64, bool:
+			record = append(record, exportAnswerText(value))
+		default:
+			record = append(record, csvText(exportAnswerText(value)))
+		}
+	}
+	return e.writer.Write(record)
+}
+
+func (e *csvExporter) Flush() error {
+	e.writer.Flush()
+	if err := e.writer.Error(); err != nil {
+		return err
+	}
+	e.flusher.Flush()
+	return nil
+}
+
+func (e *csvExporter) Close() error {
+	return e.Flush()
+}
+
+// xlsxExporter writes a single sheet through excelize's stream writer, which
+// spills large sheets to a temporary file. The workbook is sent on Close,
+// since a zip archive cannot be written before its last entry is known.
+type xlsxExporter struct {
+	file      *excelize.File
+	sheet     *excelize.StreamWriter
+	out       io.Writer
+	questions []models.RegistrationQuestion
+	row       int
+}
+
+func newXLSXExporter(w io.Writer, questions []models.RegistrationQuestion) (*xlsxExporter, error) {
+	file := excelize.NewFile()
+	if err := file.SetSheetName("Sheet1", "Attendees"); err != nil {
+		return nil, err
+	}
+
+	sheet, err := file.NewStreamWriter("Attendees")
+	if err != nil {
+		return nil, err
+	}
+
+	header := exportHeader(questions)
+	cells := make([]any, len(header))
+	for i, column := range header {
+		cells[i] = column
+	}
+	if err := sheet.SetRow("A1", cells); err != nil {
+		return nil, err
+	}
+
+	return &xlsxExporter{file: file, sheet: sheet, out: w, questions: questions, row: 1}, nil
+}
+
+func (e *xlsxExporter) WriteRow(row *attendeeExportRow) error {
+	cells := []any{row.RegistrationID, row.Name, row.Email, row.Status, row.RegisteredAt, nil}
+	if row.CheckedInAt != nil {
+		cells[5] = *row.CheckedInAt
+	}
+
+	for _, value := range exportAnswers(e.questions, row.Answers) {
+		switch value := value.(type) {
+		case float64, bool, nil:
+			cells = append(cells, value)
+		default:
+			cells = append(cells, exportAnswerText(value))
+		}
+	}
+
+	e.row++
+	cell, err := excelize.CoordinatesToCellName(1, e.row)
+	if err != nil {
+		return err
+	}
+	return e.sheet.SetRow(cell, cells)
+}
+
+func (e *xlsxExporter) Flush() error {
+	return nil
+}
+
+func (e *xlsxExporter) Close() error {
+	defer e.file.Close()
+
+	if err := e.sheet.Flush(); err != nil {
+		return err
+	}
+	return e.file.Write(e.out)
+}
+
+// ndjsonExporter writes one JSON object per line, answers included as
+// stored.
+type ndjsonExporter struct {
+	encoder *json.Encoder
+	flusher http.Flusher
+}
+
+func newNDJSONExporter(w gin.ResponseWriter) *ndjsonExporter {
+	return &ndjsonExporter{encoder: json.NewEncoder(w), flusher: w}
+}
+
+func (e *ndjsonExporter) WriteRow(row *attendeeExportRow) error {
+	if row.Answers == nil {
+		row.Answers = []models.RegistrationAnswer{}
+	}
+	return e.encoder.Encode(row)
+}
+
+func (e *ndjsonExporter) Flush() error {
+	e.flusher.Flush()
+	return nil
+}
+
+func (e *ndjsonExporter) Close() error {
+	return e.Flush()
+}
+
+// exportHeader returns the column names of a tabular export.
+func exportHeader(questions []models.RegistrationQuestion) []string {
+	header := append([]string{}, attendeeExportColumns...)
+	for _, question := range questions {
+		header = append(header, question.Label)
+	}
+	return header
+}
+
+// exportAnswers lines answers up with the form's questions, nil where a
+// question went unanswered. Answers to removed questions are left out.
+func exportAnswers(questions []models.RegistrationQuestion, answers []models.RegistrationAnswer) []any {
+	values := make([]any, len(questions))
+	for i, question := range questions {
+		for _, answer := range answers {
+			if answer.QuestionID == question.ID {
+				values[i] = answer.Value
+				break
+			}
+		}
+	}
+	return values
+}
+
+// csvText keeps user input starting with a formula character from being
+// evaluated when the CSV is opened in a spreadsheet.
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// exportAnswerText renders an answer as a spreadsheet cell. Multiple choices
+// are joined with semicolons.
+func exportAnswerText(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	case []any:
+		choices := make([]string, len(value))
+		for i, choice := range value {
+			choices[i] = exportAnswerText(choice)
+		}
+		return strings.Join(choices, "; ")
+	case []string:
+		return strings.Join(value, "; ")
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pick-cee/events-api/internal/database"
+	"github.com/pick-cee/events-api/internal/middleware"
+	"github.com/pick-cee/events-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// Attendee list views, from least to most revealing. The public sees the
+// counts and the names of attendees who opted in, attendees see everyone with
+// names redacted unless opted in, and organizers see emails and answers.
+const (
+	attendeeViewPublic    = "public"
+	attendeeViewAttendee  = "attendee"
+	attendeeViewOrganizer = "organizer"
+)
+
+type AttendeeListResponse struct {
+	View       string             `json:"view"`
+	Count      int64              `json:"count"` // confirmed attendees
+	Waitlisted int64              `json:"waitlisted"`
+	Attendees  []AttendeeResponse `json:"attendees"`
+}
+
+// AttendeeResponse is an attendee as shown in a given view. Fields the view
+// does not allow are left empty.
+type AttendeeResponse struct {
+	RegistrationID uint                        `json:"registration_id,omitempty"`
+	UserID         uint                        `json:"user_id,omitempty"`
+	Name           string                      `json:"name"`
+	Email          string                      `json:"email,omitempty"`
+	Status         string                      `json:"status"`
+	RegisteredAt   *time.Time                  `json:"registered_at,omitempty"`
+	CheckedInAt    *time.Time                  `json:"checked_in_at,omitempty"`
+	Answers        []models.RegistrationAnswer `json:"answers,omitempty"`
+}
+
+func newAttendeeResponse(registration *models.Registration, view string, viewerID uint) AttendeeResponse {
+	user := &registration.User
+
+	if view != attendeeViewOrganizer {
+		name := user.Name
+		if !user.ListPublicly && user.ID != viewerID {
+			name = redactedName(name)
+		}
+		return AttendeeResponse{Name: name, Status: registration.Status}
+	}
+
+	return AttendeeResponse{
+		RegistrationID: registration.ID,
+		UserID:         user.ID,
+		Name:           user.Name,
+		Email:          user.Email,
+		Status:         registration.Status,
+		RegisteredAt:   &registration.CreatedAt,
+		CheckedInAt:    registration.CheckedInAt,
+		Answers:        registration.Answers,
+	}
+}
+
+// attendeeView picks what the caller may see of an event's attendees.
+// Check-in staff get the attendee view; they scan tickets and need no emails.
+func attendeeView(c *gin.Context, event *models.Event) string {
+	if canManageEvent(c, event, models.CollaboratorPermissionAttendees) {
+		return attendeeViewOrganizer
+	}
+
+	if canManageEvent(c, event, models.CollaboratorPermissionCheckIn) {
+		return attendeeViewAttendee
+	}
+
+	userID := middleware.GetUserId(c)
+	if userID == 0 {
+		return attendeeViewPublic
+	}
+
+	var count int64
+	database.DB.Model(&models.Registration{}).Where("event_id = ? AND user_id = ?", event.ID, userID).
+		Scopes(admittedRegistrations).Count(&count)
+	if count > 0 {
+		return attendeeViewAttendee
+	}
+	return attendeeViewPublic
+}
+
+// listedAttendees narrows a public attendee list to users who opted in to
+// showing their name.
+func listedAttendees(db *gorm.DB) *gorm.DB {
+	return db.Where("user_id IN (?)", database.DB.Model(&models.User{}).Select("id").Where("list_publicly = ?", true))
+}
+
+// redactedName shortens a name to its first word and last initial, e.g.
+// "Ada Lovelace" to "Ada L.".
+func redactedName(name string) string {
+	words := strings.Fields(name)
+	if len(words) == 0 {
+		return ""
+	}
+
+	if len(words) == 1 {
+		return words[0]
+	}
+
+	initial, _ := utf8.DecodeRuneInString(words[len(words)-1])
+	return words[0] + " " + string(initial) + "."
+}
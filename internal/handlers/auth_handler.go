@@ -1,7 +1,14 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -12,6 +19,7 @@ import (
 	"github.com/pick-cee/events-api/internal/models"
 	"github.com/pick-cee/events-api/internal/services"
 	"github.com/pick-cee/events-api/internal/utils"
+	"gorm.io/gorm"
 )
 
 type AuthHandler struct {
@@ -31,6 +39,7 @@ type SignupRequest struct {
 	Name     string `json:"name" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
+	Locale   string `json:"locale"` // defaults to the Accept-Language header
 }
 
 type LoginRequest struct {
@@ -38,15 +47,66 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+	All          bool   `json:"all"` // log out of every session
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type UpdateProfileRequest struct {
+	Name         string `json:"name"`
+	Locale       string `json:"locale"`
+	ListPublicly *bool  `json:"list_publicly"`
+}
+
 type AuthResponse struct {
-	User  UserResponse `json:"user"`
-	Token string       `json:"token"`
+	User UserResponse `json:"user"`
+	TokenResponse
+}
+
+type TokenResponse struct {
+	Token                 string    `json:"token"`
+	ExpiresAt             time.Time `json:"expires_at"`
+	RefreshToken          string    `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
 }
 
 type UserResponse struct {
-	ID    uint   `json:"id"`
-	Name  string `json:"name"`
-	Email string `json:"email"`
+	ID            uint   `json:"id"`
+	Name          string `json:"name"`
+	Email         string `json:"email"`
+	Role          string `json:"role"`
+	Locale        string `json:"locale"`
+	EmailVerified bool   `json:"email_verified"`
+	ListPublicly  bool   `json:"list_publicly"`
+}
+
+func newUserResponse(user *models.User) UserResponse {
+	return UserResponse{
+		ID:            user.ID,
+		Name:          user.Name,
+		Email:         user.Email,
+		Role:          user.Role,
+		Locale:        user.Locale,
+		EmailVerified: user.IsEmailVerified(),
+		ListPublicly:  user.ListPublicly,
+	}
 }
 
 // sign up
@@ -65,36 +125,54 @@ func (h *AuthHandler) Signup(c *gin.Context) {
 		return
 	}
 
-	// create user
+	locale := services.DefaultLocale
+	if req.Locale != "" {
+		if locale = services.NormalizeLocale(req.Locale); locale == "" {
+			utils.ValidationErrorResponse(c, "unsupported locale")
+			return
+		}
+	} else if preferred := preferredLocale(c.GetHeader("Accept-Language")); preferred != "" {
+		locale = preferred
+	}
+
+	// create user; anyone who signs up can create events, as before roles
 	user := models.User{
 		Name:     req.Name,
 		Email:    req.Email,
 		Password: req.Password,
+		Role:     models.RoleOrganizer,
+		Locale:   locale,
 	}
 
-	if err := database.DB.Create(&user).Error; err != nil {
+	// the welcome and verification emails are queued with the user
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+
+		if err := h.emailService.SendWelcomeEmail(tx, user.Email, user.Name); err != nil {
+			return err
+		}
+
+		return h.sendVerificationEmail(tx, &user)
+	})
+	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create user")
 		return
 	}
 
 	// generate a token
-	token, err := h.generateToken(user.ID, user.Email)
+	tokens, err := h.issueTokens(&user, "")
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate token")
 		return
 	}
 
 	response := AuthResponse{
-		User: UserResponse{
-			ID:    user.ID,
-			Email: user.Email,
-			Name:  user.Name,
-		},
-		Token: token,
+		User:          newUserResponse(&user),
+		TokenResponse: tokens,
 	}
 
-	h.emailService.SendWelcomeEmail(response.User.Email, response.User.Name)
-
 	utils.SuccessResponse(c, http.StatusCreated, response)
 }
 
@@ -120,35 +198,505 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 
 	// generate token
-	token, err := h.generateToken(existingUser.ID, existingUser.Email)
+	tokens, err := h.issueTokens(&existingUser, "")
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to generate token")
 		return
 	}
 
 	response := AuthResponse{
-		User: UserResponse{
-			ID:    existingUser.ID,
-			Email: existingUser.Email,
-			Name:  existingUser.Name,
-		},
-		Token: token,
+		User:          newUserResponse(&existingUser),
+		TokenResponse: tokens,
 	}
 
 	utils.SuccessResponse(c, http.StatusOK, response)
 
 }
 
-func (h *AuthHandler) generateToken(userId uint, email string) (string, error) {
+// Refresh exchanges a refresh token for a new access token and a new refresh
+// token. Presenting a token that was already rotated is treated as theft and
+// revokes every token descended from the same login, refresh and access
+// tokens alike. The user's other sessions are left alone.
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	var current models.RefreshToken
+	if err := database.DB.Where("token_hash = ?", utils.HashToken(req.RefreshToken)).First(&current).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid refresh token")
+		return
+	}
+
+	if current.ReplacedByID != nil {
+		_ = revokeRefreshTokens(database.DB.Where("family_id = ?", current.FamilyID))
+		_ = middleware.RevokeFamily(c.Request.Context(), current.FamilyID, h.cfg.AccessTokenTTL)
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Refresh token reuse detected, please log in again")
+		return
+	}
+
+	if !current.IsActive() {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid refresh token")
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, current.UserID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid refresh token")
+		return
+	}
+
+	var tokens TokenResponse
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// only one request may rotate a given token
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", current.ID).
+			Update("revoked_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errTokenAlreadyRotated
+		}
+
+		var next *models.RefreshToken
+		var err error
+		tokens, next, err = h.issueTokensTx(tx, &user, current.FamilyID)
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&current).Update("replaced_by_id", next.ID).Error
+	})
+
+	if errors.Is(err, errTokenAlreadyRotated) {
+		utils.ErrorResponse(c, http.StatusUnauthorized, "Invalid refresh token")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to refresh token")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, tokens)
+}
+
+// Logout revokes the presented access token and refresh token. With "all" set
+// every session of the user is ended.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req LogoutRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	ctx := c.Request.Context()
+	userId := middleware.GetUserId(c)
+	claims := middleware.GetClaims(c)
+
+	if err := middleware.RevokeToken(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to log out")
+		return
+	}
+
+	if req.All {
+		if err := middleware.RevokeAllForUser(ctx, userId, h.cfg.AccessTokenTTL); err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to log out")
+			return
+		}
+
+		if err := revokeRefreshTokens(database.DB.Where("user_id = ?", userId)); err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to log out")
+			return
+		}
+
+		utils.SuccessResponse(c, http.StatusOK, gin.H{"message": "Logged out of all sessions"})
+		return
+	}
+
+	if req.RefreshToken != "" {
+		var token models.RefreshToken
+		err := database.DB.Where("token_hash = ? AND user_id = ?", utils.HashToken(req.RefreshToken), userId).First(&token).Error
+		if err == nil {
+			if err := revokeRefreshTokens(database.DB.Where("family_id = ?", token.FamilyID)); err != nil {
+				utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to log out")
+				return
+			}
+		}
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
+
+// ForgotPassword emails a password reset link. It responds the same way
+// whether or not the email belongs to an account.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("email = ?", req.Email).First(&user).Error; err == nil {
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			token, expiresAt, err := createUserToken(tx, user.ID, models.TokenPurposePasswordReset, passwordResetTTL)
+			if err != nil {
+				return err
+			}
+
+			resetURL := fmt.Sprintf("%s/reset-password?token=%s", h.cfg.AppURL, url.QueryEscape(token))
+			return h.emailService.SendPasswordResetEmail(tx, user.Email, user.Name, resetURL, expiresAt)
+		})
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create reset token")
+			return
+		}
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{"message": "If the email is registered, a reset link has been sent"})
+}
+
+// ResetPassword sets a new password using a reset token and ends every
+// existing session of the user.
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	var user models.User
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		token, err := consumeUserToken(tx, req.Token, models.TokenPurposePasswordReset)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.First(&user, token.UserID).Error; err != nil {
+			return errInvalidUserToken
+		}
+
+		if err := user.SetPassword(req.Password); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&user).UpdateColumn("password", user.Password).Error; err != nil {
+			return err
+		}
+
+		// whoever received the reset email controls the address
+		if err := h.markEmailVerified(tx, &user); err != nil {
+			return err
+		}
+
+		return revokeRefreshTokens(tx.Where("user_id = ?", user.ID))
+	})
+
+	if errors.Is(err, errInvalidUserToken) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid or expired token")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to reset password")
+		return
+	}
+
+	if err := middleware.RevokeAllForUser(c.Request.Context(), user.ID, h.cfg.AccessTokenTTL); err != nil {
+		log.Printf("❌ Failed to revoke access tokens for user %d: %v\n", user.ID, err)
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
+
+// VerifyEmail marks the user's email as verified using a verification token.
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req VerifyEmailRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		token, err := consumeUserToken(tx, req.Token, models.TokenPurposeEmailVerification)
+		if err != nil {
+			return err
+		}
+
+		var user models.User
+		if err := tx.First(&user, token.UserID).Error; err != nil {
+			return err
+		}
+		return h.markEmailVerified(tx, &user)
+	})
+
+	if errors.Is(err, errInvalidUserToken) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid or expired token")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to verify email")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
+
+// markEmailVerified records that the user proved they own their address and
+// promotes them if it is a configured admin email. Admins get the role only
+// then, since anyone can sign up with an address.
+func (h *AuthHandler) markEmailVerified(tx *gorm.DB, user *models.User) error {
+	if !user.IsEmailVerified() {
+		now := time.Now()
+		if err := tx.Model(user).UpdateColumn("email_verified_at", now).Error; err != nil {
+			return err
+		}
+		user.EmailVerifiedAt = &now
+	}
+
+	if !slices.Contains(h.cfg.AdminEmails, strings.ToLower(user.Email)) || user.Role == models.RoleAdmin {
+		return nil
+	}
+	return tx.Model(user).UpdateColumn("role", models.RoleAdmin).Error
+}
+
+// GetProfile returns the current user.
+func (h *AuthHandler) GetProfile(c *gin.Context) {
+	var user models.User
+	if err := database.DB.First(&user, middleware.GetUserId(c)).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "User not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, newUserResponse(&user))
+}
+
+// UpdateProfile changes the current user's name or notification locale.
+func (h *AuthHandler) UpdateProfile(c *gin.Context) {
+	var req UpdateProfileRequest
+
+	var user models.User
+	if err := database.DB.First(&user, middleware.GetUserId(c)).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	updates := map[string]any{}
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.Locale != "" {
+		locale := services.NormalizeLocale(req.Locale)
+		if locale == "" {
+			utils.ValidationErrorResponse(c, "unsupported locale")
+			return
+		}
+		updates["locale"] = locale
+	}
+	if req.ListPublicly != nil {
+		updates["list_publicly"] = *req.ListPublicly
+	}
+
+	if err := database.DB.Model(&user).Updates(updates).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update profile")
+		return
+	}
+
+	database.DB.First(&user, user.ID)
+
+	utils.SuccessResponse(c, http.StatusOK, newUserResponse(&user))
+}
+
+// ResendVerificationEmail sends a fresh verification link to the current user.
+func (h *AuthHandler) ResendVerificationEmail(c *gin.Context) {
+	var user models.User
+	if err := database.DB.First(&user, middleware.GetUserId(c)).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if user.IsEmailVerified() {
+		utils.ErrorResponse(c, http.StatusConflict, "Email already verified")
+		return
+	}
+
+	if err := h.sendVerificationEmail(database.DB, &user); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to send verification email")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{"message": "Verification email sent"})
+}
+
+// sendVerificationEmail issues a verification token and queues the email
+// carrying it, both through tx.
+func (h *AuthHandler) sendVerificationEmail(tx *gorm.DB, user *models.User) error {
+	return tx.Transaction(func(tx *gorm.DB) error {
+		token, expiresAt, err := createUserToken(tx, user.ID, models.TokenPurposeEmailVerification, emailVerificationTTL)
+		if err != nil {
+			return err
+		}
+
+		verifyURL := fmt.Sprintf("%s/verify-email?token=%s", h.cfg.AppURL, url.QueryEscape(token))
+		return h.emailService.SendEmailVerificationEmail(tx, user.Email, user.Name, verifyURL, expiresAt)
+	})
+}
+
+// issueTokens creates an access token and a refresh token. An empty familyID
+// starts a new refresh token family.
+func (h *AuthHandler) issueTokens(user *models.User, familyID string) (TokenResponse, error) {
+	tokens, _, err := h.issueTokensTx(database.DB, user, familyID)
+	return tokens, err
+}
+
+func (h *AuthHandler) issueTokensTx(tx *gorm.DB, user *models.User, familyID string) (TokenResponse, *models.RefreshToken, error) {
+	refreshToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return TokenResponse{}, nil, err
+	}
+
+	if familyID == "" {
+		if familyID, err = utils.GenerateRandomToken(16); err != nil {
+			return TokenResponse{}, nil, err
+		}
+	}
+
+	accessToken, expiresAt, err := h.generateToken(user, familyID)
+	if err != nil {
+		return TokenResponse{}, nil, err
+	}
+
+	record := models.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(refreshToken),
+		FamilyID:  familyID,
+		ExpiresAt: time.Now().Add(h.cfg.RefreshTokenTTL),
+	}
+
+	if err := tx.Create(&record).Error; err != nil {
+		return TokenResponse{}, nil, err
+	}
+
+	return TokenResponse{
+		Token:                 accessToken,
+		ExpiresAt:             expiresAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: record.ExpiresAt,
+	}, &record, nil
+}
+
+func (h *AuthHandler) generateToken(user *models.User, familyID string) (string, time.Time, error) {
+	jti, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(h.cfg.AccessTokenTTL)
+
 	claims := middleware.Claims{
-		UserID: userId,
-		Email:  email,
+		UserID:   user.ID,
+		Email:    user.Email,
+		Role:     user.Role,
+		FamilyID: familyID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(h.cfg.JWTSecret))
+	signed, err := token.SignedString([]byte(h.cfg.JWTSecret))
+	return signed, expiresAt, err
+}
+
+var (
+	errTokenAlreadyRotated = errors.New("refresh token already rotated")
+	errInvalidUserToken    = errors.New("invalid or expired token")
+)
+
+// revokeRefreshTokens revokes every still-active refresh token matched by query.
+func revokeRefreshTokens(query *gorm.DB) error {
+	return query.Model(&models.RefreshToken{}).
+		Where("revoked_at IS NULL").
+		Update("revoked_at", time.Now()).Error
+}
+
+const (
+	passwordResetTTL     = time.Hour
+	emailVerificationTTL = 48 * time.Hour
+)
+
+// createUserToken issues a new single-use token for the purpose, replacing any
+// unused token the user still holds for it. Only the hash is stored.
+func createUserToken(tx *gorm.DB, userID uint, purpose string, ttl time.Duration) (string, time.Time, error) {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	record := models.UserToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+
+	err = tx.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+			Delete(&models.UserToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&record).Error
+	})
+
+	return token, record.ExpiresAt, err
+}
+
+// consumeUserToken marks a valid token as used. Marking is conditional so the
+// same token cannot be redeemed twice concurrently.
+func consumeUserToken(tx *gorm.DB, token, purpose string) (*models.UserToken, error) {
+	var record models.UserToken
+	if err := tx.Where("token_hash = ? AND purpose = ?", utils.HashToken(token), purpose).First(&record).Error; err != nil {
+		return nil, errInvalidUserToken
+	}
+
+	if record.UsedAt != nil || time.Now().After(record.ExpiresAt) {
+		return nil, errInvalidUserToken
+	}
+
+	result := tx.Model(&models.UserToken{}).Where("id = ? AND used_at IS NULL", record.ID).Update("used_at", time.Now())
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errInvalidUserToken
+	}
+
+	return &record, nil
+}
+
+// preferredLocale picks the first supported language from an Accept-Language
+// header, ignoring quality weights beyond their order.
+func preferredLocale(acceptLanguage string) string {
+	for _, tag := range strings.Split(acceptLanguage, ",") {
+		tag, _, _ = strings.Cut(tag, ";")
+		if locale := services.NormalizeLocale(tag); locale != "" {
+			return locale
+		}
+	}
+	return ""
 }
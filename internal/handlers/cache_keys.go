@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/pick-cee/events-api/internal/cache"
+)
+
+// every ListEvents page lives under cache.EventListNamespace, bumped by any
+// write that can move an event onto, off or between pages, since that shifts
+// every later page. Pages are also tagged with the events they hold, so other
+// writes only evict the pages showing a changed event.
+//
+// listedEventColumns are the event columns ListEvents filters or sorts on.
+var listedEventColumns = []string{"title", "description", "location", "visibility", "date_time"}
+
+// movesInLists reports whether changing the columns can move an event in
+// the list pages.
+func movesInLists(columns []string) bool {
+	return slices.ContainsFunc(columns, func(column string) bool {
+		return slices.Contains(listedEventColumns, column)
+	})
+}
+
+func eventCacheKey(id any) string {
+	return fmt.Sprintf("events:id=%v", id)
+}
+
+func userRegistrationsCacheKey(userID uint) string {
+	return fmt.Sprintf("event_registrations:userId=%v", userID)
+}
+
+// eventTag marks every cached response that embeds the event.
+func eventTag(id uint) string {
+	return fmt.Sprintf("event:%d", id)
+}
+
+// userRegistrationsTag marks cached responses listing a user's registrations.
+func userRegistrationsTag(userID uint) string {
+	return fmt.Sprintf("user:%d:registrations", userID)
+}
+
+// eventTags returns the tags of the events.
+func eventTags(eventIDs ...uint) []string {
+	tags := make([]string, len(eventIDs))
+	for i, id := range eventIDs {
+		tags[i] = eventTag(id)
+	}
+	return tags
+}
+
+// invalidateEvents evicts every cached response embedding one of the events,
+// including the list pages and attendees' registration lists showing them.
+func invalidateEvents(ctx context.Context, eventIDs ...uint) {
+	if err := cache.InvalidateTags(ctx, eventTags(eventIDs...)...); err != nil {
+		log.Printf("❌ Failed to invalidate event cache: %v\n", err)
+	}
+}
+
+// invalidateEventLists drops every list page, for writes that move events in
+// listings, such as creating, deleting or cancelling one, changing a column
+// the lists filter or sort on, or a registration changing its popularity.
+func invalidateEventLists(ctx context.Context) {
+	if err := cache.BumpNamespace(ctx, cache.EventListNamespace); err != nil {
+		log.Printf("❌ Failed to invalidate event list cache: %v\n", err)
+	}
+}
+
+// invalidateRegistrations evicts the cached registration lists of the users
+// along with the events whose attendee lists changed, and the list pages
+// since the events' popularity may have changed.
+func invalidateRegistrations(ctx context.Context, userIDs []uint, eventIDs ...uint) {
+	tags := make([]string, len(userIDs))
+	for i, id := range userIDs {
+		tags[i] = userRegistrationsTag(id)
+	}
+
+	if err := cache.InvalidateTags(ctx, tags...); err != nil {
+		log.Printf("❌ Failed to invalidate registration cache: %v\n", err)
+	}
+
+	invalidateEvents(ctx, eventIDs...)
+	invalidateEventLists(ctx)
+}
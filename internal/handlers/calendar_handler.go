@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pick-cee/events-api/internal/config"
+	"github.com/pick-cee/events-api/internal/database"
+	"github.com/pick-cee/events-api/internal/ical"
+	"github.com/pick-cee/events-api/internal/middleware"
+	"github.com/pick-cee/events-api/internal/models"
+	"github.com/pick-cee/events-api/internal/services"
+	"github.com/pick-cee/events-api/internal/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	calendarContentType = "text/calendar; charset=utf-8"
+	// cancelledFeedWindow is how long a cancelled registration or deleted
+	// event stays in feeds, so subscribed calendars see the cancellation
+	cancelledFeedWindow = 90 * 24 * time.Hour
+	// feedRefreshInterval is how often calendar apps are asked to refresh
+	feedRefreshInterval = time.Hour
+)
+
+type CalendarHandler struct {
+	cfg *config.Config
+}
+
+func NewCalendarHandler(cfg *config.Config) *CalendarHandler {
+	return &CalendarHandler{
+		cfg: cfg,
+	}
+}
+
+type CalendarFeedResponse struct {
+	URL       string    `json:"url"`
+	WebcalURL string    `json:"webcal_url"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GetEventCalendar downloads an event as an .ics file, to anyone who can see
+// the event.
+func (h *CalendarHandler) GetEventCalendar(c *gin.Context) {
+	var event models.Event
+	if err := database.DB.Preload("Reminders", orderByOffset).First(&event, c.Param("id")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	if view, _ := eventAccess(c, h.cfg.JWTSecret, &event); !view {
+		utils.ErrorResponse(c, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	calendar := ical.Calendar{
+		Method: "PUBLISH",
+		Events: []ical.Event{services.CalendarEvent(h.cfg, &event, ical.StatusConfirmed, time.Time{})},
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="event-%d.ics"`, event.ID))
+	c.Data(http.StatusOK, calendarContentType, calendar.Bytes())
+}
+
+// CreateCalendarFeed issues the URL of the user's calendar feed. The URL is
+// shown only once: calling it again replaces the URL, and the old one stops
+// working.
+func (h *CalendarHandler) CreateCalendarFeed(c *gin.Context) {
+	userID := middleware.GetUserId(c)
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create calendar feed")
+		return
+	}
+
+	var feed models.CalendarFeed
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).First(&feed).Error; err != nil {
+			feed = models.CalendarFeed{UserID: userID}
+		}
+
+		feed.TokenHash = utils.HashToken(token)
+		feed.LastFetchedAt = nil
+		feed.CreatedAt = time.Now()
+		return tx.Save(&feed).Error
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create calendar feed")
+		return
+	}
+
+	feedURL := fmt.Sprintf("%s/api/v1/calendar/feed/%s", h.cfg.APIURL, token)
+	webcalURL := feedURL
+	if _, address, found := strings.Cut(feedURL, "://"); found {
+		webcalURL = "webcal://" + address
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, CalendarFeedResponse{
+		URL:       feedURL,
+		WebcalURL: webcalURL,
+		CreatedAt: feed.CreatedAt,
+	})
+}
+
+// GetCalendarFeedStatus tells whether the user has a feed and when it was
+// last fetched, without its URL.
+func (h *CalendarHandler) GetCalendarFeedStatus(c *gin.Context) {
+	var feed models.CalendarFeed
+	if err := database.DB.Where("user_id = ?", middleware.GetUserId(c)).First(&feed).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "No calendar feed")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, feed)
+}
+
+// DeleteCalendarFeed turns the user's feed off; its URL stops working.
+func (h *CalendarHandler) DeleteCalendarFeed(c *gin.Context) {
+	result := database.DB.Where("user_id = ?", middleware.GetUserId(c)).Delete(&models.CalendarFeed{})
+	if result.Error != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to delete calendar feed")
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, "No calendar feed")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{"message": "Calendar feed deleted successfully"})
+}
+
+// GetCalendarFeed serves the calendar of the feed whose token is in the path:
+// every event the user registered for, as GetMyRegistrations lists them.
+// Pending and waitlisted registrations are tentative. Cancelled
+// registrations, rejected applications and deleted events stay for a while as
+// cancelled entries, so subscribed calendars drop them.
+func (h *CalendarHandler) GetCalendarFeed(c *gin.Context) {
+	var feed models.CalendarFeed
+	if err := database.DB.Where("token_hash = ?", utils.HashToken(c.Param("token"))).First(&feed).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Calendar feed not found")
+		return
+	}
+
+	cancelledSince := time.Now().Add(-cancelledFeedWindow)
+
+	var registrations []models.Registration
+	if err := database.DB.Unscoped().
+		Where("user_id = ? AND (deleted_at IS NULL OR deleted_at > ?)", feed.UserID, cancelledSince).
+		Preload("Event", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Event.Reminders", orderByOffset).
+		Order("created_at ASC, id ASC").
+		Find(&registrations).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch calendar feed")
+		return
+	}
+
+	// a user who cancelled and registered again has both registrations; the
+	// newest decides the entry
+	entries := map[uint]ical.Event{}
+	var order []uint
+	for i := range registrations {
+		registration := &registrations[i]
+		event := &registration.Event
+		if event.ID == 0 || (event.DeletedAt.Valid && event.DeletedAt.Time.Before(cancelledSince)) {
+			continue
+		}
+
+		if _, seen := entries[event.ID]; !seen {
+			order = append(order, event.ID)
+		}
+		status, changedAt := feedEntryStatus(registration)
+		entries[event.ID] = services.CalendarEvent(h.cfg, event, status, changedAt)
+	}
+
+	calendar := ical.Calendar{
+		Name:            "My events",
+		RefreshInterval: feedRefreshInterval,
+	}
+	for _, id := range order {
+		calendar.Events = append(calendar.Events, entries[id])
+	}
+
+	database.DB.Model(&feed).UpdateColumn("last_fetched_at", time.Now())
+
+	c.Header("Cache-Control", "private, no-store")
+	c.Data(http.StatusOK, calendarContentType, calendar.Bytes())
+}
+
+// feedEntryStatus maps a registration to the status of its feed entry, with
+// the time the registration last changed. A registration made after a
+// cancellation thereby gets a higher SEQUENCE than the cancelled entry.
+func feedEntryStatus(registration *models.Registration) (string, time.Time) {
+	if registration.DeletedAt.Valid {
+		return ical.StatusCancelled, registration.DeletedAt.Time
+	}
+
+	changedAt := registration.CreatedAt
+	if registration.ReviewedAt != nil && registration.ReviewedAt.After(changedAt) {
+		changedAt = *registration.ReviewedAt
+	}
+
+	switch registration.Status {
+	case models.RegistrationStatusConfirmed:
+		return ical.StatusConfirmed, changedAt
+	case models.RegistrationStatusRejected:
+		return ical.StatusCancelled, changedAt
+	default:
+		return ical.StatusTentative, changedAt
+	}
+}
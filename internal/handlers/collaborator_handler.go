@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pick-cee/events-api/internal/config"
+	"github.com/pick-cee/events-api/internal/database"
+	"github.com/pick-cee/events-api/internal/middleware"
+	"github.com/pick-cee/events-api/internal/models"
+	"github.com/pick-cee/events-api/internal/services"
+	"github.com/pick-cee/events-api/internal/utils"
+	"gorm.io/gorm"
+)
+
+type CollaboratorHandler struct {
+	cfg          *config.Config
+	emailService *services.EmailService
+}
+
+func NewCollaboratorHandler(cfg *config.Config, emailService *services.EmailService) *CollaboratorHandler {
+	return &CollaboratorHandler{
+		cfg:          cfg,
+		emailService: emailService,
+	}
+}
+
+// Request/Response DTOs
+type InviteCollaboratorRequest struct {
+	Email      string `json:"email" binding:"required,email"`
+	Permission string `json:"permission" binding:"required,oneof=edit attendees checkin"`
+}
+
+type UpdateCollaboratorRequest struct {
+	Permission string `json:"permission" binding:"required,oneof=edit attendees checkin"`
+}
+
+// InviteCollaborator invites someone by email to help manage an event.
+// Re-inviting an existing collaborator updates their permission.
+func (h *CollaboratorHandler) InviteCollaborator(c *gin.Context) {
+	var request InviteCollaboratorRequest
+
+	var event models.Event
+	if err := database.DB.First(&event, c.Param("id")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	if !isEventOwner(c, event.CreatorID) {
+		utils.ErrorResponse(c, http.StatusForbidden, "Only the event creator can invite collaborators")
+		return
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	email := strings.ToLower(request.Email)
+
+	var inviter models.User
+	if err := database.DB.First(&inviter, middleware.GetUserId(c)).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "User not found")
+		return
+	}
+
+	if strings.EqualFold(inviter.Email, email) {
+		utils.ValidationErrorResponse(c, "You cannot invite yourself")
+		return
+	}
+
+	var collaborator models.EventCollaborator
+	err := database.DB.Where("event_id = ? AND email = ?", event.ID, email).First(&collaborator).Error
+	if err != nil {
+		collaborator = models.EventCollaborator{
+			EventID: event.ID,
+			Email:   email,
+		}
+	}
+
+	collaborator.Permission = request.Permission
+	collaborator.InvitedByID = inviter.ID
+	if collaborator.Status != models.CollaboratorStatusAccepted {
+		collaborator.Status = models.CollaboratorStatusPending
+		collaborator.RespondedAt = nil
+	}
+
+	var invitee models.User
+	if err := database.DB.Where("LOWER(email) = ?", email).First(&invitee).Error; err == nil {
+		collaborator.UserID = &invitee.ID
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&collaborator).Error; err != nil {
+			return err
+		}
+
+		if collaborator.Status != models.CollaboratorStatusPending {
+			return nil
+		}
+
+		if collaborator.UserID != nil {
+			if wants, err := services.WantsEmail(tx, *collaborator.UserID, services.NotificationCollaboratorInvitation); err != nil || !wants {
+				return err
+			}
+		}
+
+		acceptURL := fmt.Sprintf("%s/collaborations/%d", h.cfg.AppURL, collaborator.ID)
+		return h.emailService.SendCollaboratorInvitationEmail(tx, email, inviter.Name, &event, collaborator.Permission, acceptURL)
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to invite collaborator")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, collaborator)
+}
+
+// ListCollaborators is visible to the event's owners and collaborators.
+func (h *CollaboratorHandler) ListCollaborators(c *gin.Context) {
+	var event models.Event
+	if err := database.DB.First(&event, c.Param("id")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	if !canManageEvent(c, &event, models.CollaboratorPermissionCheckIn) {
+		utils.ErrorResponse(c, http.StatusForbidden, "You do not have access to this event")
+		return
+	}
+
+	var collaborators []models.EventCollaborator
+	if err := database.DB.Where("event_id = ?", event.ID).Preload("User").Order("id ASC").Find(&collaborators).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch collaborators")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, collaborators)
+}
+
+func (h *CollaboratorHandler) UpdateCollaborator(c *gin.Context) {
+	var request UpdateCollaboratorRequest
+
+	event, collaborator, ok := h.loadCollaborator(c)
+	if !ok {
+		return
+	}
+
+	if !isEventOwner(c, event.CreatorID) {
+		utils.ErrorResponse(c, http.StatusForbidden, "Only the event creator can change collaborators")
+		return
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	if err := database.DB.Model(collaborator).Update("permission", request.Permission).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update collaborator")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, collaborator)
+}
+
+// RemoveCollaborator lets the event's owners remove a collaborator, and a
+// collaborator step down themselves.
+func (h *CollaboratorHandler) RemoveCollaborator(c *gin.Context) {
+	event, collaborator, ok := h.loadCollaborator(c)
+	if !ok {
+		return
+	}
+
+	isSelf := collaborator.UserID != nil && *collaborator.UserID == middleware.GetUserId(c)
+	if !isSelf && !isEventOwner(c, event.CreatorID) {
+		utils.ErrorResponse(c, http.StatusForbidden, "Only the event creator can remove collaborators")
+		return
+	}
+
+	if err := database.DB.Delete(collaborator).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to remove collaborator")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{"message": "Collaborator removed successfully"})
+}
+
+// GetMyCollaborations lists invitations addressed to the current user, once
+// they verified the address the invitations were sent to.
+func (h *CollaboratorHandler) GetMyCollaborations(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	if !user.IsEmailVerified() {
+		utils.ErrorResponse(c, http.StatusForbidden, "Verify your email address to see the invitations sent to it")
+		return
+	}
+
+	var collaborations []models.EventCollaborator
+	if err := database.DB.Where("LOWER(email) = ? AND status <> ?", strings.ToLower(user.Email), models.CollaboratorStatusDeclined).
+		Preload("Event").Order("id DESC").Find(&collaborations).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch collaborations")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, collaborations)
+}
+
+func (h *CollaboratorHandler) AcceptInvitation(c *gin.Context) {
+	h.respondToInvitation(c, models.CollaboratorStatusAccepted)
+}
+
+func (h *CollaboratorHandler) DeclineInvitation(c *gin.Context) {
+	h.respondToInvitation(c, models.CollaboratorStatusDeclined)
+}
+
+// respondToInvitation records the invitee's answer. Only the user whose email
+// the invitation was sent to may answer it, and only after verifying that
+// address; anyone can sign up with an address that has no account yet.
+func (h *CollaboratorHandler) respondToInvitation(c *gin.Context, status string) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	if !user.IsEmailVerified() {
+		utils.ErrorResponse(c, http.StatusForbidden, "Verify your email address to answer this invitation")
+		return
+	}
+
+	var collaborator models.EventCollaborator
+	if err := database.DB.First(&collaborator, c.Param("id")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Invitation not found")
+		return
+	}
+
+	if !strings.EqualFold(collaborator.Email, user.Email) {
+		utils.ErrorResponse(c, http.StatusForbidden, "This invitation was sent to another user")
+		return
+	}
+
+	if collaborator.Status != models.CollaboratorStatusPending {
+		utils.ErrorResponse(c, http.StatusConflict, "Invitation already answered")
+		return
+	}
+
+	now := time.Now()
+	collaborator.Status = status
+	collaborator.UserID = &user.ID
+	collaborator.RespondedAt = &now
+
+	if err := database.DB.Save(&collaborator).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to answer invitation")
+		return
+	}
+
+	database.DB.Preload("Event").First(&collaborator, collaborator.ID)
+
+	utils.SuccessResponse(c, http.StatusOK, collaborator)
+}
+
+func (h *CollaboratorHandler) loadCollaborator(c *gin.Context) (*models.Event, *models.EventCollaborator, bool) {
+	var event models.Event
+	if err := database.DB.First(&event, c.Param("id")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Event not found")
+		return nil, nil, false
+	}
+
+	var collaborator models.EventCollaborator
+	if err := database.DB.Where("event_id = ?", event.ID).First(&collaborator, c.Param("collaboratorId")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Collaborator not found")
+		return nil, nil, false
+	}
+
+	return &event, &collaborator, true
+}
+
+// currentUser loads the authenticated user, responding with 404 if the
+// account no longer exists.
+func currentUser(c *gin.Context) (*models.User, bool) {
+	var user models.User
+	if err := database.DB.First(&user, middleware.GetUserId(c)).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "User not found")
+		return nil, false
+	}
+	return &user, true
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/pick-cee/events-api/internal/database"
+	"github.com/pick-cee/events-api/internal/middleware"
+	"github.com/pick-cee/events-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// invitationQuery is the query parameter carrying the token of an event
+// invitation link.
+const invitationQuery = "invite"
+
+// isEventOwner reports whether the current user created the event or series,
+// or holds a role that manages all events.
+func isEventOwner(c *gin.Context, creatorID uint) bool {
+	return creatorID == middleware.GetUserId(c) || middleware.HasPermission(c, models.PermissionManageAllEvents)
+}
+
+// canManageEvent reports whether the current user may perform an action that
+// needs the given collaborator permission: owners always can, collaborators
+// only once they accepted an invitation that covers the permission.
+func canManageEvent(c *gin.Context, event *models.Event, permission string) bool {
+	if isEventOwner(c, event.CreatorID) {
+		return true
+	}
+
+	userID := middleware.GetUserId(c)
+	if userID == 0 {
+		return false
+	}
+
+	var collaborator models.EventCollaborator
+	if err := database.DB.Where("event_id = ? AND user_id = ?", event.ID, userID).First(&collaborator).Error; err != nil {
+		return false
+	}
+
+	return collaborator.Allows(permission)
+}
+
+// eventAccess reports whether the caller may see an event and register for
+// it. Public and unlisted events are open to everyone. Private events are open
+// to their organizers and registrants, and to invitees as far as their
+// invitation allows, found through the caller's verified email or the
+// ?invite= token of an invitation link.
+func eventAccess(c *gin.Context, secret string, event *models.Event) (view, register bool) {
+	if !event.IsPrivate() {
+		return true, true
+	}
+
+	if canManageEvent(c, event, models.CollaboratorPermissionCheckIn) {
+		return true, true
+	}
+
+	if userID := middleware.GetUserId(c); userID != 0 {
+		var registrations int64
+		if err := database.DB.Model(&models.Registration{}).Where("event_id = ? AND user_id = ? AND status <> ?", event.ID, userID, models.RegistrationStatusRejected).Count(&registrations).Error; err == nil && registrations > 0 {
+			return true, true
+		}
+
+		// invitations addressed to the user's email count before they accept,
+		// once the email is verified; until then only the link proves it
+		var invitations []models.EventInvitation
+		database.DB.Where("event_id = ? AND (user_id = ? OR LOWER(email) = (SELECT LOWER(email) FROM users WHERE id = ? AND email_verified_at IS NOT NULL))", event.ID, userID, userID).Find(&invitations)
+		for _, invitation := range invitations {
+			if invitation.Expired() {
+				continue
+			}
+			view = true
+			register = register || invitation.AllowsRegistration()
+		}
+		if register {
+			return view, register
+		}
+	}
+
+	if invitation, ok := linkedInvitation(c, secret, event.ID); ok {
+		return true, register || invitation.AllowsRegistration()
+	}
+
+	return view, register
+}
+
+// linkedInvitation loads the active invitation to the event whose link the
+// request came from. Once an invitation is accepted, its link only works for
+// the user who accepted it.
+func linkedInvitation(c *gin.Context, secret string, eventID uint) (*models.EventInvitation, bool) {
+	token := c.Query(invitationQuery)
+	if token == "" {
+		return nil, false
+	}
+
+	id, ok := parseInvitationToken(secret, token)
+	if !ok {
+		return nil, false
+	}
+
+	var invitation models.EventInvitation
+	if err := database.DB.Where("event_id = ?", eventID).First(&invitation, id).Error; err != nil {
+		return nil, false
+	}
+
+	if invitation.Expired() {
+		return nil, false
+	}
+
+	if invitation.UserID != nil && *invitation.UserID != middleware.GetUserId(c) {
+		return nil, false
+	}
+
+	return &invitation, true
+}
+
+// listedEvents narrows a query to the events shown in public listings, so the
+// shared list cache never holds an unlisted or private event.
+func listedEvents(db *gorm.DB) *gorm.DB {
+	return db.Where("events.visibility = ?", models.EventVisibilityPublic)
+}
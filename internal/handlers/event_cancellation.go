@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pick-cee/events-api/internal/database"
+	"github.com/pick-cee/events-api/internal/models"
+	"github.com/pick-cee/events-api/internal/services"
+	"github.com/pick-cee/events-api/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errEventCancelled = errors.New("event cancelled")
+	errEventEnded     = errors.New("event ended")
+)
+
+type CancelEventRequest struct {
+	Reason string `json:"reason" binding:"max=1000"`
+}
+
+// CancelEvent calls an event off. Unlike DeleteEvent it keeps the event
+// visible with its status and reason, emails every registrant, and stops its
+// reminders. Registrations are kept, so attendees still find the event among
+// their registrations. The body is optional.
+func (h *EventHandler) CancelEvent(c *gin.Context) {
+	var event models.Event
+	if err := database.DB.First(&event, c.Param("id")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	if !canManageEvent(c, &event, models.CollaboratorPermissionEdit) {
+		utils.ErrorResponse(c, http.StatusForbidden, "You can only cancel your own events")
+		return
+	}
+
+	var request CancelEventRequest
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	var registrants []uint
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		registrants, err = cancelEvent(tx, h.emailService, &event, request.Reason)
+		return err
+	})
+
+	switch {
+	case errors.Is(err, errEventCancelled):
+		utils.ErrorResponse(c, http.StatusConflict, "Event is already cancelled")
+		return
+	case errors.Is(err, errEventEnded):
+		utils.ErrorResponse(c, http.StatusConflict, "Event has already ended")
+		return
+	case err != nil:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to cancel event")
+		return
+	}
+
+	invalidateRegistrations(c.Request.Context(), registrants, event.ID)
+
+	database.DB.Preload("Creator").First(&event, event.ID)
+
+	utils.SuccessResponse(c, http.StatusOK, event)
+}
+
+// cancelEvent locks an event and calls it off: it records the status and
+// reason, stops the reminders still queued and emails every registrant. It
+// returns the registrants' user ids, or errEventCancelled or errEventEnded
+// when there is nothing to call off.
+func cancelEvent(tx *gorm.DB, emailService *services.EmailService, event *models.Event, reason string) ([]uint, error) {
+	// lock the event so no registration slips in while registrants are notified
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(event, event.ID).Error; err != nil {
+		return nil, err
+	}
+
+	if event.IsCancelled() {
+		return nil, errEventCancelled
+	}
+	if event.EndTime.Before(time.Now()) {
+		return nil, errEventEnded
+	}
+
+	now := time.Now()
+	event.Status = models.EventStatusCancelled
+	event.CancelledAt = &now
+	event.CancelReason = reason
+
+	if err := tx.Model(event).Updates(map[string]any{
+		"status":        event.Status,
+		"cancelled_at":  now,
+		"cancel_reason": reason,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	if err := skipQueuedReminders(tx, event.ID); err != nil {
+		return nil, err
+	}
+
+	var registrations []models.Registration
+	if err := tx.Preload("User").
+		Where("event_id = ? AND status <> ?", event.ID, models.RegistrationStatusRejected).
+		Order("created_at ASC, id ASC").Find(&registrations).Error; err != nil {
+		return nil, err
+	}
+
+	registrants := make([]uint, len(registrations))
+	for i, registration := range registrations {
+		registrants[i] = registration.UserID
+	}
+
+	optedOut, err := services.OptedOutUsers(tx, services.NotificationEventCancelled, registrants)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, registration := range registrations {
+		if optedOut[registration.UserID] {
+			continue
+		}
+
+		user := registration.User
+		confirmed := registration.Status == models.RegistrationStatusConfirmed
+		if err := emailService.SendEventCancelledEmail(tx, user.Email, user.Name, event, confirmed); err != nil {
+			return nil, err
+		}
+	}
+	return registrants, nil
+}
+
+// skipQueuedReminders stops the reminders of an event that were queued but not
+// yet sent: their outbox messages and ledger entries are marked skipped.
+// Messages a worker already claimed are left to finish.
+func skipQueuedReminders(tx *gorm.DB, eventID uint) error {
+	var messageIDs []uint
+	// locking keeps the outbox worker from claiming them until this commits
+	if err := tx.Model(&models.OutboxMessage{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("status = ? AND id IN (?)", models.OutboxStatusPending,
+			tx.Model(&models.ReminderDelivery{}).Select("outbox_message_id").Where("event_id = ? AND status = ?", eventID, models.ReminderStatusQueued)).
+		Pluck("id", &messageIDs).Error; err != nil {
+		return err
+	}
+
+	if len(messageIDs) == 0 {
+		return nil
+	}
+
+	if err := tx.Model(&models.OutboxMessage{}).Where("id IN ?", messageIDs).
+		Update("status", models.OutboxStatusSkipped).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&models.ReminderDelivery{}).Where("outbox_message_id IN ?", messageIDs).
+		Update("status", models.ReminderStatusSkipped).Error
+}
+
+// notifyRescheduled emails an event's registrants when an update moved it in
+// time or to another place, comparing against the event as it was before.
+// Reminders already sent for the old start are owed again when they now fall
+// due later, so their ledger entries are cleared.
+func notifyRescheduled(tx *gorm.DB, emailService *services.EmailService, event, previous *models.Event) error {
+	moved := !event.DateTime.Equal(previous.DateTime) || !event.EndTime.Equal(previous.EndTime)
+	if !moved && event.Location == previous.Location {
+		return nil
+	}
+
+	// nobody needs to hear about a past or cancelled event moving
+	if event.IsCancelled() || event.EndTime.Before(time.Now()) {
+		return nil
+	}
+
+	if moved {
+		minutesToStart := int(time.Until(event.DateTime) / time.Minute)
+		if err := tx.Where("event_id = ? AND status <> ? AND offset_minutes < ?", event.ID, models.ReminderStatusQueued, minutesToStart).
+			Delete(&models.ReminderDelivery{}).Error; err != nil {
+			return err
+		}
+	}
+
+	var registrations []models.Registration
+	if err := tx.Preload("User").
+		Where("event_id = ? AND status <> ?", event.ID, models.RegistrationStatusRejected).
+		Order("created_at ASC, id ASC").Find(&registrations).Error; err != nil {
+		return err
+	}
+
+	userIDs := make([]uint, len(registrations))
+	for i, registration := range registrations {
+		userIDs[i] = registration.UserID
+	}
+
+	optedOut, err := services.OptedOutUsers(tx, services.NotificationEventRescheduled, userIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, registration := range registrations {
+		if optedOut[registration.UserID] {
+			continue
+		}
+
+		user := registration.User
+		confirmed := registration.Status == models.RegistrationStatusConfirmed
+		if err := emailService.SendEventRescheduledEmail(tx, user.Email, user.Name, event, previous, confirmed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pick-cee/events-api/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// searchVector must match the expression of the idx_events_search index
+// created in database.Migrate, otherwise PostgreSQL cannot use the index.
+const searchVector = "to_tsvector('english', coalesce(title, '') || ' ' || coalesce(description, ''))"
+
+const popularityColumn = "(SELECT COUNT(*) FROM registrations WHERE registrations.event_id = events.id AND registrations.status = 'confirmed' AND registrations.deleted_at IS NULL)"
+
+// EventFilters holds the ListEvents query parameters.
+type EventFilters struct {
+	When      string // upcoming (default), past or all
+	Status    string // scheduled or cancelled, empty for both
+	From      time.Time
+	To        time.Time
+	Location  string
+	CreatorID uint
+	Query     string
+	Sort      string // date (default), created, popularity or relevance
+	Order     string // asc or desc
+}
+
+func GetEventFilters(c *gin.Context) (EventFilters, error) {
+	filters := EventFilters{
+		When:     strings.ToLower(c.DefaultQuery("when", "upcoming")),
+		Status:   strings.ToLower(c.Query("status")),
+		Location: strings.TrimSpace(c.Query("location")),
+		Query:    strings.TrimSpace(c.Query("q")),
+		Sort:     strings.ToLower(c.DefaultQuery("sort", "date")),
+		Order:    strings.ToLower(c.Query("order")),
+	}
+
+	switch filters.When {
+	case "upcoming", "past", "all":
+	default:
+		return filters, fmt.Errorf("when must be one of upcoming, past or all")
+	}
+
+	switch filters.Status {
+	case "", models.EventStatusScheduled, models.EventStatusCancelled:
+	default:
+		return filters, fmt.Errorf("status must be scheduled or cancelled")
+	}
+
+	for param, dest := range map[string]*time.Time{"from": &filters.From, "to": &filters.To} {
+		if value := c.Query(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filters, fmt.Errorf("%s must be an RFC 3339 timestamp", param)
+			}
+			*dest = t
+		}
+	}
+
+	if !filters.From.IsZero() && !filters.To.IsZero() && filters.To.Before(filters.From) {
+		return filters, fmt.Errorf("to must not be before from")
+	}
+
+	// an explicit range replaces the default upcoming window
+	if (!filters.From.IsZero() || !filters.To.IsZero()) && c.Query("when") == "" {
+		filters.When = "all"
+	}
+
+	if value := c.Query("creator_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return filters, fmt.Errorf("creator_id must be a number")
+		}
+		filters.CreatorID = uint(id)
+	}
+
+	switch filters.Sort {
+	case "date", "created", "popularity":
+	case "relevance":
+		if filters.Query == "" {
+			return filters, fmt.Errorf("sort=relevance requires q")
+		}
+	default:
+		return filters, fmt.Errorf("sort must be one of date, created, popularity or relevance")
+	}
+
+	switch filters.Order {
+	case "asc", "desc":
+	case "":
+		// soonest first for upcoming events, most recent first otherwise
+		filters.Order = "desc"
+		if filters.Sort == "date" && filters.When != "past" {
+			filters.Order = "asc"
+		}
+	default:
+		return filters, fmt.Errorf("order must be asc or desc")
+	}
+
+	return filters, nil
+}
+
+// Where narrows a query to the matching events.
+func (f EventFilters) Where(db *gorm.DB) *gorm.DB {
+	now := time.Now()
+
+	switch f.When {
+	case "upcoming":
+		db = db.Where("events.date_time >= ?", now)
+	case "past":
+		db = db.Where("events.date_time < ?", now)
+	}
+
+	if f.Status != "" {
+		db = db.Where("events.status = ?", f.Status)
+	}
+
+	if !f.From.IsZero() {
+		db = db.Where("events.date_time >= ?", f.From)
+	}
+
+	if !f.To.IsZero() {
+		db = db.Where("events.date_time <= ?", f.To)
+	}
+
+	if f.Location != "" {
+		db = db.Where("events.location ILIKE ?", "%"+escapeLike(f.Location)+"%")
+	}
+
+	if f.CreatorID != 0 {
+		db = db.Where("events.creator_id = ?", f.CreatorID)
+	}
+
+	if f.Query != "" {
+		db = db.Where(searchVector+" @@ websearch_to_tsquery('english', ?)", f.Query)
+	}
+
+	return db
+}
+
+// OrderBy sorts a query according to the filters. Ties are broken by id so
+// pages are stable.
+func (f EventFilters) OrderBy(db *gorm.DB) *gorm.DB {
+	direction := strings.ToUpper(f.Order)
+
+	switch f.Sort {
+	case "created":
+		db = db.Order("events.created_at " + direction)
+	case "popularity":
+		db = db.Order(popularityColumn + " " + direction)
+	case "relevance":
+		// the rank needs a bound parameter, which only a full ORDER BY expression allows
+		return db.Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:                "ts_rank(" + searchVector + ", websearch_to_tsquery('english', ?)) " + direction + ", events.id " + direction,
+			Vars:               []interface{}{f.Query},
+			WithoutParentheses: true,
+		}})
+	default:
+		db = db.Order("events.date_time " + direction)
+	}
+
+	return db.Order("events.id " + direction)
+}
+
+// CacheKey encodes every filter so differently filtered pages never share a
+// cache entry.
+func (f EventFilters) CacheKey() string {
+	return fmt.Sprintf("when=%s:status=%s:from=%s:to=%s:location=%s:creator=%d:q=%s:sort=%s:order=%s",
+		f.When, f.Status, formatFilterTime(f.From), formatFilterTime(f.To),
+		strings.ToLower(f.Location), f.CreatorID, f.Query, f.Sort, f.Order)
+}
+
+func formatFilterTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return strconv.FormatInt(t.Unix(), 10)
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
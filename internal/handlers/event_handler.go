@@ -1,75 +1,124 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/pick-cee/events-api/internal/cache"
+	"github.com/pick-cee/events-api/internal/config"
 	"github.com/pick-cee/events-api/internal/database"
 	"github.com/pick-cee/events-api/internal/middleware"
 	"github.com/pick-cee/events-api/internal/models"
+	"github.com/pick-cee/events-api/internal/services"
 	"github.com/pick-cee/events-api/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-type EventHandler struct{}
+type EventHandler struct {
+	cfg          *config.Config
+	emailService *services.EmailService
+}
 
-func NewEventHandler() *EventHandler {
-	return &EventHandler{}
+func NewEventHandler(cfg *config.Config, emailService *services.EmailService) *EventHandler {
+	return &EventHandler{
+		cfg:          cfg,
+		emailService: emailService,
+	}
 }
 
 // Request/Response DTOs
 type CreateEventRequest struct {
-	Title       string    `json:"title" binding:"required"`
-	Description string    `json:"description"`
-	Location    string    `json:"location" binding:"required"`
-	DateTime    time.Time `json:"date_time" binding:"required"`
+	Title            string                        `json:"title" binding:"required"`
+	Description      string                        `json:"description"`
+	Location         string                        `json:"location" binding:"required"`
+	DateTime         time.Time                     `json:"date_time" binding:"required"`
+	EndTime          time.Time                     `json:"end_time"`
+	DurationMinutes  int                           `json:"duration_minutes" binding:"omitempty,min=1"`
+	TimeZone         string                        `json:"time_zone"`
+	Capacity         int                           `json:"capacity" binding:"min=0"`
+	Visibility       string                        `json:"visibility" binding:"omitempty,oneof=public unlisted private"`
+	RequiresApproval bool                          `json:"requires_approval"`
+	Questions        []RegistrationQuestionRequest `json:"questions" binding:"dive"`
+	// ReminderOffsets are minutes before the start; omitted means the
+	// default 24 hour and one hour reminders, an empty list means none
+	ReminderOffsets []int `json:"reminder_offsets_minutes"`
 }
 
 type UpdateEventRequest struct {
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Location    string    `json:"location"`
-	DateTime    time.Time `json:"date_time"`
+	Title           string    `json:"title"`
+	Description     string    `json:"description"`
+	Location        string    `json:"location"`
+	DateTime        time.Time `json:"date_time"`
+	EndTime         time.Time `json:"end_time"`
+	DurationMinutes int       `json:"duration_minutes" binding:"omitempty,min=1"`
+	TimeZone        string    `json:"time_zone"`
+	Capacity        *int      `json:"capacity" binding:"omitempty,min=0"`
+	Visibility      string    `json:"visibility" binding:"omitempty,oneof=public unlisted private"`
+	// RequiresApproval only affects later registrations; pending ones still
+	// need a decision
+	RequiresApproval *bool `json:"requires_approval"`
 }
 
+// ListEvents lists public events only; unlisted and private events are
+// reached by their link.
 func (h *EventHandler) ListEvents(c *gin.Context) {
 	params := utils.GetPaginationParams(c.Request)
-	cacheKey := fmt.Sprintf("events:page=%d:limit=%d", params.Page, params.Limit)
+	filters, err := GetEventFilters(c)
+	if err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
 	ctx := c.Request.Context()
+	namespace, cacheErr := cache.Namespace(ctx, cache.EventListNamespace)
+	cacheKey := fmt.Sprintf("%s:page=%d:limit=%d:%s", namespace, params.Page, params.Limit, filters.CacheKey())
 
 	var cached utils.PaginatedResponse[models.Event]
-	if err := cache.Get(ctx, cacheKey, &cached); err == nil {
-		utils.SuccessResponse(c, http.StatusOK, cached)
-		return
+	if cacheErr == nil {
+		if err := cache.Get(ctx, cacheKey, &cached); err == nil {
+			utils.SuccessResponse(c, http.StatusOK, cached)
+			return
+		}
 	}
 
 	var events []models.Event
 	var total int64
 
 	// Count total
-	if err := database.DB.Model(&models.Event{}).Count(&total).Error; err != nil {
+	if err := database.DB.Model(&models.Event{}).Scopes(listedEvents, filters.Where).Count(&total).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to count events")
 		return
 	}
 
 	// preload creator information
-	if err := database.DB.Scopes(utils.Paginate(params)).Preload("Creator").Find(&events).Error; err != nil {
+	if err := database.DB.Scopes(listedEvents, filters.Where, filters.OrderBy, utils.Paginate(params)).Preload("Creator").Find(&events).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch events")
 		return
 	}
 
 	response := utils.NewPaginationResponse(events, total, params)
 
-	_ = cache.Set(ctx, cacheKey, response, 5*time.Minute)
+	// without the namespace version the page could outlive the next invalidation
+	if cacheErr == nil {
+		ids := make([]uint, len(events))
+		for i, event := range events {
+			ids[i] = event.ID
+		}
+		_ = cache.SetWithTags(ctx, cacheKey, response, 5*time.Minute, eventTags(ids...)...)
+	}
 
 	utils.SuccessResponse(c, http.StatusOK, response)
 }
 
+// GetEventById returns an event to anyone allowed to see it. Private events
+// are never cached, so a cache hit is always safe to serve.
 func (h *EventHandler) GetEventById(c *gin.Context) {
 	id := c.Param("id")
-	cacheKey := fmt.Sprintf("events:id=%v", id)
+	cacheKey := eventCacheKey(id)
 	ctx := c.Request.Context()
 
 	var cached models.Event
@@ -79,12 +128,22 @@ func (h *EventHandler) GetEventById(c *gin.Context) {
 	}
 
 	var event models.Event
-	if err := database.DB.Preload("Creator").Preload("Registrations.User").First(&event, id).Error; err != nil {
+	// attendees are left out: the response is shared through the cache, and
+	// GetEventAttendees shows each caller only what they may see
+	if err := database.DB.Preload("Creator").Preload("Reminders", orderByOffset).Preload("Questions", orderByPosition).First(&event, id).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	// hide private events from those without access rather than confirm they exist
+	if view, _ := eventAccess(c, h.cfg.JWTSecret, &event); !view {
 		utils.ErrorResponse(c, http.StatusNotFound, "Event not found")
 		return
 	}
 
-	_ = cache.Set(ctx, cacheKey, event, 5*time.Minute)
+	if !event.IsPrivate() {
+		_ = cache.SetWithTags(ctx, cacheKey, event, 5*time.Minute, eventTag(event.ID))
+	}
 
 	utils.SuccessResponse(c, http.StatusOK, event)
 }
@@ -102,20 +161,58 @@ func (h *EventHandler) CreateEvent(c *gin.Context) {
 	userId := middleware.GetUserId(c)
 
 	event := models.Event{
-		Title:       request.Title,
-		Description: request.Description,
-		Location:    request.Location,
-		CreatorID:   userId,
-		DateTime:    request.DateTime,
+		Title:            request.Title,
+		Description:      request.Description,
+		Location:         request.Location,
+		CreatorID:        userId,
+		DateTime:         request.DateTime,
+		Capacity:         request.Capacity,
+		Visibility:       request.Visibility,
+		RequiresApproval: request.RequiresApproval,
+	}
+
+	if event.Visibility == "" {
+		event.Visibility = models.EventVisibilityPublic
+	}
+	event.Status = models.EventStatusScheduled
+
+	if err := setEventTimes(&event, request.EndTime, request.DurationMinutes, request.TimeZone); err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	offsets := request.ReminderOffsets
+	if offsets == nil {
+		offsets = models.DefaultReminderOffsets
+	}
+
+	reminders, err := reminderSchedule(offsets)
+	if err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
 	}
+	event.Reminders = reminders
+
+	questions, err := registrationForm(request.Questions)
+	if err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+	for i := range questions {
+		// ids are assigned on creation
+		questions[i].ID = 0
+	}
+	event.Questions = questions
 
 	if err := database.DB.Create(&event).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "An error occured while trying to create events")
 		return
 	}
 
+	invalidateEventLists(c.Request.Context())
+
 	// Load creator info
-	database.DB.Preload("Creator").First(&event, event.ID)
+	database.DB.Preload("Creator").Preload("Reminders", orderByOffset).Preload("Questions", orderByPosition).First(&event, event.ID)
 
 	utils.SuccessResponse(c, http.StatusCreated, event)
 }
@@ -123,7 +220,6 @@ func (h *EventHandler) CreateEvent(c *gin.Context) {
 func (h *EventHandler) UpdateEvent(c *gin.Context) {
 	var request UpdateEventRequest
 	id := c.Param("id")
-	userID := middleware.GetUserId(c)
 
 	var event models.Event
 	if err := database.DB.First(&event, id).Error; err != nil {
@@ -131,48 +227,86 @@ func (h *EventHandler) UpdateEvent(c *gin.Context) {
 		return
 	}
 
-	// Check if user is the creator
-	if event.CreatorID != userID {
+	// Check if user is the creator, an admin or a collaborator allowed to edit
+	if !canManageEvent(c, &event, models.CollaboratorPermissionEdit) {
 		utils.ErrorResponse(c, http.StatusForbidden, "You can only update your own events")
 		return
 	}
 
-	if err := c.ShouldBindJSON(&request); err != nil {
-		utils.ValidationErrorResponse(c, err.Error())
+	if event.IsCancelled() {
+		utils.ErrorResponse(c, http.StatusConflict, "Cancelled events cannot be updated")
 		return
 	}
 
-	// update field if required
-	if request.Title != "" {
-		event.Title = request.Title
-	}
-
-	if request.Description != "" {
-		event.Description = request.Description
-	}
-
-	if request.Location != "" {
-		event.Location = request.Location
-	}
-
-	if !request.DateTime.IsZero() {
-		event.DateTime = request.DateTime
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
 	}
 
-	if err := database.DB.Save(&event).Error; err != nil {
+	// registrants hear about a new time or place in the same transaction
+	var invalid error
+	var columns []string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// re-read under lock so a concurrent cancellation or edit is not
+		// overwritten, and seat counting does not race with registrations
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&event, event.ID).Error; err != nil {
+			return err
+		}
+
+		if event.IsCancelled() {
+			return errEventCancelled
+		}
+
+		previous := event
+		if invalid = applyEventUpdate(&event, &request); invalid != nil {
+			return invalid
+		}
+		if request.Capacity != nil {
+			event.Capacity = *request.Capacity
+		}
+
+		columns = changedEventColumns(&previous, &event)
+		if len(columns) > 0 {
+			if err := tx.Model(&event).Select(append(columns, "updated_at")).Updates(&event).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := notifyRescheduled(tx, h.emailService, &event, &previous); err != nil {
+			return err
+		}
+
+		if request.Capacity == nil {
+			return nil
+		}
+		return promoteWaitlisted(tx, h.emailService, &event)
+	})
+
+	switch {
+	case invalid != nil:
+		utils.ValidationErrorResponse(c, invalid.Error())
+		return
+	case errors.Is(err, errEventCancelled):
+		utils.ErrorResponse(c, http.StatusConflict, "Cancelled events cannot be updated")
+		return
+	case err != nil:
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update event")
 		return
 	}
 
+	invalidateEvents(c.Request.Context(), event.ID)
+	if movesInLists(columns) {
+		invalidateEventLists(c.Request.Context())
+	}
+
 	database.DB.Preload("Creator").First(&event, event.ID)
 
 	utils.SuccessResponse(c, http.StatusOK, event)
 }
 
-// Deletes event only by creator
+// Deletes event only by creator, admin or an editing collaborator
 func (h *EventHandler) DeleteEvent(c *gin.Context) {
 	id := c.Param("id")
-	userId := middleware.GetUserId(c)
 
 	var event models.Event
 	if err := database.DB.First(&event, id).Error; err != nil {
@@ -180,9 +314,9 @@ func (h *EventHandler) DeleteEvent(c *gin.Context) {
 		return
 	}
 
-	// Check if user is the creator
-	if event.CreatorID != userId {
-		utils.ErrorResponse(c, http.StatusForbidden, "You can only update your own events")
+	// Check if user is the creator, an admin or a collaborator allowed to edit
+	if !canManageEvent(c, &event, models.CollaboratorPermissionEdit) {
+		utils.ErrorResponse(c, http.StatusForbidden, "You can only delete your own events")
 		return
 	}
 
@@ -191,5 +325,110 @@ func (h *EventHandler) DeleteEvent(c *gin.Context) {
 		return
 	}
 
+	invalidateEvents(c.Request.Context(), event.ID)
+	invalidateEventLists(c.Request.Context())
+
 	utils.SuccessResponse(c, http.StatusOK, gin.H{"message": "Event deleted successfully"})
 }
+
+// setEventTimes fills in the end time from either an explicit end or a
+// duration, then validates the schedule. Events default to UTC.
+func setEventTimes(event *models.Event, endTime time.Time, durationMinutes int, timeZone string) error {
+	if timeZone != "" {
+		event.TimeZone = timeZone
+	}
+	if event.TimeZone == "" {
+		event.TimeZone = "UTC"
+	}
+
+	switch {
+	case !endTime.IsZero():
+		event.EndTime = endTime
+	case durationMinutes > 0:
+		event.EndTime = event.DateTime.Add(time.Duration(durationMinutes) * time.Minute)
+	}
+
+	return validateEventTimes(event)
+}
+
+// applyEventUpdate copies the non-empty fields of an update request onto an
+// event. Moving the start keeps the duration unless a new end is supplied.
+// Capacity is left to the caller since it needs the event row locked.
+func applyEventUpdate(event *models.Event, request *UpdateEventRequest) error {
+	if request.Title != "" {
+		event.Title = request.Title
+	}
+
+	if request.Description != "" {
+		event.Description = request.Description
+	}
+
+	if request.Location != "" {
+		event.Location = request.Location
+	}
+
+	if request.Visibility != "" {
+		event.Visibility = request.Visibility
+	}
+
+	if request.RequiresApproval != nil {
+		event.RequiresApproval = *request.RequiresApproval
+	}
+
+	if !request.DateTime.IsZero() {
+		duration := event.Duration()
+		event.DateTime = request.DateTime
+		event.EndTime = request.DateTime.Add(duration)
+	}
+
+	return setEventTimes(event, request.EndTime, request.DurationMinutes, request.TimeZone)
+}
+
+// changedEventColumns lists the columns an update changed, so it only writes
+// those and leaves the rest of the row as other writers left it.
+func changedEventColumns(before, after *models.Event) []string {
+	var columns []string
+	changed := func(column string, differs bool) {
+		if differs {
+			columns = append(columns, column)
+		}
+	}
+
+	changed("title", before.Title != after.Title)
+	changed("description", before.Description != after.Description)
+	changed("location", before.Location != after.Location)
+	changed("visibility", before.Visibility != after.Visibility)
+	changed("requires_approval", before.RequiresApproval != after.RequiresApproval)
+	changed("date_time", !before.DateTime.Equal(after.DateTime))
+	changed("end_time", !before.EndTime.Equal(after.EndTime))
+	changed("time_zone", before.TimeZone != after.TimeZone)
+	changed("capacity", before.Capacity != after.Capacity)
+	changed("series_id", !equalPointers(before.SeriesID, after.SeriesID, func(a, b uint) bool { return a == b }))
+	changed("recurrence_id", !equalPointers(before.RecurrenceID, after.RecurrenceID, time.Time.Equal))
+	return columns
+}
+
+// equalPointers reports whether two optional values are both unset, or both
+// set and equal.
+func equalPointers[T any](a, b *T, equal func(T, T) bool) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return equal(*a, *b)
+}
+
+func validateEventTimes(event *models.Event) error {
+	if _, err := time.LoadLocation(event.TimeZone); err != nil {
+		return fmt.Errorf("unknown time_zone %q", event.TimeZone)
+	}
+
+	if event.EndTime.IsZero() {
+		return errors.New("end_time or duration_minutes is required")
+	}
+
+	if !event.EndTime.After(event.DateTime) {
+		return errors.New("end_time must be after date_time")
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pick-cee/events-api/internal/database"
+	"github.com/pick-cee/events-api/internal/models"
+	"github.com/pick-cee/events-api/internal/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxReminders bounds how many reminders one event can schedule
+	maxReminders = 10
+	// maxReminderOffset is the earliest a reminder can go out: 30 days ahead
+	maxReminderOffset = 30 * 24 * 60
+)
+
+type UpdateEventRemindersRequest struct {
+	OffsetsMinutes []int `json:"offsets_minutes" binding:"required"`
+}
+
+// GetEventReminders lists an event's reminder schedule to those who can edit it.
+func (h *EventHandler) GetEventReminders(c *gin.Context) {
+	var event models.Event
+	if err := database.DB.Preload("Reminders", orderByOffset).First(&event, c.Param("id")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	if !canManageEvent(c, &event, models.CollaboratorPermissionEdit) {
+		utils.ErrorResponse(c, http.StatusForbidden, "You can only manage reminders of your own events")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, event.Reminders)
+}
+
+// UpdateEventReminders replaces an event's reminder schedule. Reminders that
+// were already sent are not sent again, even if their offset is kept.
+func (h *EventHandler) UpdateEventReminders(c *gin.Context) {
+	var event models.Event
+	if err := database.DB.First(&event, c.Param("id")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	if !canManageEvent(c, &event, models.CollaboratorPermissionEdit) {
+		utils.ErrorResponse(c, http.StatusForbidden, "You can only manage reminders of your own events")
+		return
+	}
+
+	var request UpdateEventRemindersRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	reminders, err := reminderSchedule(request.OffsetsMinutes)
+	if err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("event_id = ?", event.ID).Delete(&models.EventReminder{}).Error; err != nil {
+			return err
+		}
+
+		if len(reminders) == 0 {
+			return nil
+		}
+
+		for i := range reminders {
+			reminders[i].EventID = event.ID
+		}
+		return tx.Create(&reminders).Error
+	})
+
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update reminders")
+		return
+	}
+
+	invalidateEvents(c.Request.Context(), event.ID)
+
+	utils.SuccessResponse(c, http.StatusOK, reminders)
+}
+
+// ListReminderDeliveries pages through an event's reminder ledger, newest
+// first, optionally filtered by status and offset.
+func (h *EventHandler) ListReminderDeliveries(c *gin.Context) {
+	var event models.Event
+	if err := database.DB.First(&event, c.Param("id")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	if !canManageEvent(c, &event, models.CollaboratorPermissionEdit) {
+		utils.ErrorResponse(c, http.StatusForbidden, "You can only manage reminders of your own events")
+		return
+	}
+
+	params := utils.GetPaginationParams(c.Request)
+
+	query := database.DB.Model(&models.ReminderDelivery{}).Where("event_id = ?", event.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if offset := c.Query("offset_minutes"); offset != "" {
+		query = query.Where("offset_minutes = ?", offset)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to count reminder deliveries")
+		return
+	}
+
+	var deliveries []models.ReminderDelivery
+	if err := query.Scopes(utils.Paginate(params)).Order("id DESC").Find(&deliveries).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch reminder deliveries")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, utils.NewPaginationResponse(deliveries, total, params))
+}
+
+// reminderSchedule validates offsets in minutes and turns them into
+// reminders, dropping duplicates and ordering them earliest first.
+func reminderSchedule(offsets []int) ([]models.EventReminder, error) {
+	offsets = slices.Clone(offsets)
+	slices.Sort(offsets)
+	offsets = slices.Compact(offsets)
+
+	if len(offsets) > maxReminders {
+		return nil, fmt.Errorf("at most %d reminders can be scheduled", maxReminders)
+	}
+
+	for _, offset := range offsets {
+		if offset < 1 || offset > maxReminderOffset {
+			return nil, fmt.Errorf("reminder offsets must be between 1 and %d minutes", maxReminderOffset)
+		}
+	}
+
+	slices.Reverse(offsets)
+	return models.NewEventReminders(offsets), nil
+}
+
+func orderByOffset(db *gorm.DB) *gorm.DB {
+	return db.Order("offset_minutes DESC")
+}
@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pick-cee/events-api/internal/config"
+	"github.com/pick-cee/events-api/internal/database"
+	"github.com/pick-cee/events-api/internal/middleware"
+	"github.com/pick-cee/events-api/internal/models"
+	"github.com/pick-cee/events-api/internal/services"
+	"github.com/pick-cee/events-api/internal/utils"
+	"gorm.io/gorm"
+)
+
+// errInvitationTaken is returned when another user already accepted an
+// invitation.
+var errInvitationTaken = errors.New("invitation accepted by another user")
+
+type InvitationHandler struct {
+	cfg          *config.Config
+	emailService *services.EmailService
+}
+
+func NewInvitationHandler(cfg *config.Config, emailService *services.EmailService) *InvitationHandler {
+	return &InvitationHandler{
+		cfg:          cfg,
+		emailService: emailService,
+	}
+}
+
+// Request/Response DTOs
+type InviteAttendeesRequest struct {
+	Emails    []string   `json:"emails" binding:"required,min=1,max=100,dive,email"`
+	Access    string     `json:"access" binding:"omitempty,oneof=view register"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type AcceptEventInvitationRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// InviteAttendees emails invitation links to an event. On a private event the
+// link lets its holder view the event and, with register access (the
+// default), register. Re-inviting an address updates its invitation and sends
+// the link again.
+func (h *InvitationHandler) InviteAttendees(c *gin.Context) {
+	var request InviteAttendeesRequest
+
+	var event models.Event
+	if err := database.DB.First(&event, c.Param("id")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	if !canManageEvent(c, &event, models.CollaboratorPermissionAttendees) {
+		utils.ErrorResponse(c, http.StatusForbidden, "You are not allowed to invite attendees to this event")
+		return
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	if request.Access == "" {
+		request.Access = models.InvitationAccessRegister
+	}
+
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		utils.ValidationErrorResponse(c, "expires_at must be in the future")
+		return
+	}
+
+	inviter, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	invitations := []models.EventInvitation{}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		seen := map[string]bool{}
+		for _, email := range request.Emails {
+			email = strings.ToLower(email)
+			if seen[email] {
+				continue
+			}
+			seen[email] = true
+
+			var invitation models.EventInvitation
+			if err := tx.Where("event_id = ? AND email = ?", event.ID, email).First(&invitation).Error; err != nil {
+				invitation = models.EventInvitation{
+					EventID: event.ID,
+					Email:   email,
+				}
+			}
+
+			invitation.Access = request.Access
+			invitation.InvitedByID = inviter.ID
+			invitation.ExpiresAt = request.ExpiresAt
+
+			if err := tx.Save(&invitation).Error; err != nil {
+				return err
+			}
+			invitations = append(invitations, invitation)
+
+			var invitee models.User
+			if err := tx.Where("LOWER(email) = ?", email).First(&invitee).Error; err == nil {
+				if wants, err := services.WantsEmail(tx, invitee.ID, services.NotificationEventInvitation); err != nil {
+					return err
+				} else if !wants {
+					continue
+				}
+			}
+
+			inviteURL := fmt.Sprintf("%s/events/%d?%s=%s", h.cfg.AppURL, event.ID, invitationQuery, url.QueryEscape(invitationToken(h.cfg.JWTSecret, invitation.ID)))
+			if err := h.emailService.SendEventInvitationEmail(tx, email, inviter.Name, &event, invitation.AllowsRegistration(), inviteURL); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to invite attendees")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, invitations)
+}
+
+// ListInvitations is visible to the event's owners and collaborators who
+// manage attendees.
+func (h *InvitationHandler) ListInvitations(c *gin.Context) {
+	var event models.Event
+	if err := database.DB.First(&event, c.Param("id")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	if !canManageEvent(c, &event, models.CollaboratorPermissionAttendees) {
+		utils.ErrorResponse(c, http.StatusForbidden, "You are not allowed to manage attendees of this event")
+		return
+	}
+
+	var invitations []models.EventInvitation
+	if err := database.DB.Where("event_id = ?", event.ID).Preload("User").Order("id ASC").Find(&invitations).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch invitations")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, invitations)
+}
+
+// RevokeInvitation deletes an invitation, which invalidates its link.
+// Registrations already made with it are kept.
+func (h *InvitationHandler) RevokeInvitation(c *gin.Context) {
+	var event models.Event
+	if err := database.DB.First(&event, c.Param("id")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	if !canManageEvent(c, &event, models.CollaboratorPermissionAttendees) {
+		utils.ErrorResponse(c, http.StatusForbidden, "You are not allowed to manage attendees of this event")
+		return
+	}
+
+	var invitation models.EventInvitation
+	if err := database.DB.Where("event_id = ?", event.ID).First(&invitation, c.Param("invitationId")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Invitation not found")
+		return
+	}
+
+	if err := database.DB.Delete(&invitation).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to revoke invitation")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{"message": "Invitation revoked successfully"})
+}
+
+// AcceptEventInvitation binds the invitation of a link to the current user, so
+// they keep their access without the link. Links can be forwarded, so any
+// signed-in user may accept one that nobody accepted yet.
+func (h *InvitationHandler) AcceptEventInvitation(c *gin.Context) {
+	var request AcceptEventInvitationRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	id, ok := parseInvitationToken(h.cfg.JWTSecret, request.Token)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid invitation token")
+		return
+	}
+
+	var invitation models.EventInvitation
+	if err := database.DB.First(&invitation, id).Error; err != nil || invitation.Expired() {
+		utils.ErrorResponse(c, http.StatusNotFound, "Invitation not found or expired")
+		return
+	}
+
+	err := acceptEventInvitation(database.DB, &invitation, middleware.GetUserId(c))
+	switch {
+	case errors.Is(err, errInvitationTaken):
+		utils.ErrorResponse(c, http.StatusConflict, "Invitation already accepted by another user")
+		return
+	case err != nil:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to accept invitation")
+		return
+	}
+
+	database.DB.Preload("Event").First(&invitation, invitation.ID)
+
+	utils.SuccessResponse(c, http.StatusOK, invitation)
+}
+
+// acceptEventInvitation binds an invitation to a user unless another user
+// accepted it first.
+func acceptEventInvitation(tx *gorm.DB, invitation *models.EventInvitation, userID uint) error {
+	if invitation.UserID != nil {
+		if *invitation.UserID != userID {
+			return errInvitationTaken
+		}
+		return nil
+	}
+
+	now := time.Now()
+	result := tx.Model(&models.EventInvitation{}).
+		Where("id = ? AND user_id IS NULL", invitation.ID).
+		Updates(map[string]any{"user_id": userID, "accepted_at": now})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errInvitationTaken
+	}
+
+	invitation.UserID = &userID
+	invitation.AcceptedAt = &now
+	return nil
+}
+
+// invitationToken signs the token of an invitation link. It stays valid until
+// the invitation expires or is revoked.
+func invitationToken(secret string, invitationID uint) string {
+	return utils.SignToken(secret, fmt.Sprintf("event-invitation:%d", invitationID))
+}
+
+// parseInvitationToken verifies a token created by invitationToken.
+func parseInvitationToken(secret, token string) (uint, bool) {
+	data, ok := utils.VerifySignedToken(secret, token)
+	if !ok {
+		return 0, false
+	}
+
+	id, found := strings.CutPrefix(data, "event-invitation:")
+	if !found {
+		return 0, false
+	}
+
+	invitationID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return uint(invitationID), true
+}
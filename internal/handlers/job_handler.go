@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pick-cee/events-api/internal/database"
+	"github.com/pick-cee/events-api/internal/models"
+	"github.com/pick-cee/events-api/internal/scheduler"
+	"github.com/pick-cee/events-api/internal/utils"
+)
+
+type JobHandler struct {
+	scheduler *scheduler.Scheduler
+}
+
+func NewJobHandler(scheduler *scheduler.Scheduler) *JobHandler {
+	return &JobHandler{
+		scheduler: scheduler,
+	}
+}
+
+// ListJobs lists the scheduled jobs with their state, next run and last run.
+func (h *JobHandler) ListJobs(c *gin.Context) {
+	jobs, err := h.scheduler.Jobs()
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch jobs")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, jobs)
+}
+
+// ListJobRuns lists job runs, newest first, optionally filtered by job and
+// status.
+func (h *JobHandler) ListJobRuns(c *gin.Context) {
+	params := utils.GetPaginationParams(c.Request)
+
+	query := database.DB.Model(&models.JobRun{})
+	if job := c.Query("job"); job != "" {
+		query = query.Where("job = ?", job)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to count job runs")
+		return
+	}
+
+	var runs []models.JobRun
+	if err := query.Scopes(utils.Paginate(params)).Order("started_at DESC, id DESC").Find(&runs).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch job runs")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, utils.NewPaginationResponse(runs, total, params))
+}
+
+func (h *JobHandler) GetJobRun(c *gin.Context) {
+	var run models.JobRun
+	if err := database.DB.First(&run, c.Param("id")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Job run not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, run)
+}
+
+// TriggerJob starts a run of a job in the background and returns its history
+// entry, which can be polled for the outcome.
+func (h *JobHandler) TriggerJob(c *gin.Context) {
+	run, err := h.scheduler.Trigger(c.Param("name"))
+	switch {
+	case errors.Is(err, scheduler.ErrJobNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "Job not found")
+		return
+	case errors.Is(err, scheduler.ErrJobRunning):
+		utils.ErrorResponse(c, http.StatusConflict, "Job is already running")
+		return
+	case err != nil:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to start job")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusAccepted, run)
+}
+
+func (h *JobHandler) PauseJob(c *gin.Context) {
+	h.setPaused(c, true)
+}
+
+func (h *JobHandler) ResumeJob(c *gin.Context) {
+	h.setPaused(c, false)
+}
+
+func (h *JobHandler) setPaused(c *gin.Context, paused bool) {
+	job, err := h.scheduler.SetPaused(c.Param("name"), paused)
+	switch {
+	case errors.Is(err, scheduler.ErrJobNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "Job not found")
+		return
+	case err != nil:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update job")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, job)
+}
@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"html/template"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pick-cee/events-api/internal/config"
+	"github.com/pick-cee/events-api/internal/database"
+	"github.com/pick-cee/events-api/internal/middleware"
+	"github.com/pick-cee/events-api/internal/models"
+	"github.com/pick-cee/events-api/internal/services"
+	"github.com/pick-cee/events-api/internal/utils"
+	"gorm.io/gorm"
+)
+
+// unsubscribePage asks a browser following an unsubscribe link to confirm,
+// posting the form back to the same link.
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>Unsubscribe</title></head>
+<body>
+<p>Stop receiving {{if eq .Type "all"}}all optional{{else}}"{{.Type}}"{{end}} notifications by {{.Channel}}?</p>
+<form method="post" action="{{.Action}}"><button type="submit">Unsubscribe</button></form>
+</body>
+</html>
+`))
+
+type NotificationHandler struct {
+	cfg *config.Config
+}
+
+func NewNotificationHandler(cfg *config.Config) *NotificationHandler {
+	return &NotificationHandler{
+		cfg: cfg,
+	}
+}
+
+// Request/Response DTOs
+type PreviewNotificationRequest struct {
+	Payload map[string]any `json:"payload"`
+}
+
+type NotificationPreferenceRequest struct {
+	Type    string `json:"type" binding:"required"`
+	Channel string `json:"channel" binding:"required,oneof=email"`
+	Enabled *bool  `json:"enabled" binding:"required"`
+}
+
+type UpdateNotificationPreferencesRequest struct {
+	Preferences []NotificationPreferenceRequest `json:"preferences" binding:"required,dive"`
+}
+
+type NotificationPreferenceResponse struct {
+	Type    string `json:"type"`
+	Channel string `json:"channel"`
+	Enabled bool   `json:"enabled"`
+}
+
+type NotificationTemplateResponse struct {
+	Type    string   `json:"type"`
+	Locales []string `json:"locales"`
+}
+
+// ListNotificationTemplates lists every notification type with the locales it
+// has been translated into.
+func (h *NotificationHandler) ListNotificationTemplates(c *gin.Context) {
+	templates := []NotificationTemplateResponse{}
+	for _, notificationType := range services.NotificationTypes() {
+		templates = append(templates, NotificationTemplateResponse{
+			Type:    notificationType,
+			Locales: services.TemplateLocales(notificationType),
+		})
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, templates)
+}
+
+// PreviewNotification renders a notification with sample data in the
+// requested locale. POST accepts a payload whose fields override the sample.
+// ?format=html or ?format=text returns the rendered body as is.
+func (h *NotificationHandler) PreviewNotification(c *gin.Context) {
+	notificationType := c.Param("type")
+
+	payload, ok := services.SamplePayload(notificationType)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusNotFound, "Unknown notification type")
+		return
+	}
+
+	if c.Request.Method == http.MethodPost {
+		var request PreviewNotificationRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			utils.ValidationErrorResponse(c, err.Error())
+			return
+		}
+		maps.Copy(payload, request.Payload)
+	}
+
+	locale := c.DefaultQuery("locale", services.DefaultLocale)
+	if services.NormalizeLocale(locale) == "" {
+		utils.ValidationErrorResponse(c, "unsupported locale")
+		return
+	}
+
+	message, err := services.RenderNotification(services.Notification{
+		ID:        "preview",
+		Type:      notificationType,
+		Recipient: "preview@example.com",
+		Locale:    locale,
+		Payload:   payload,
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	switch c.Query("format") {
+	case "html":
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(message.HTML))
+	case "text":
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(message.Text))
+	default:
+		utils.SuccessResponse(c, http.StatusOK, message)
+	}
+}
+
+// GetNotificationPreferences lists every optional notification type, plus
+// "all", with whether the current user receives it.
+func (h *NotificationHandler) GetNotificationPreferences(c *gin.Context) {
+	preferences, err := loadNotificationPreferences(middleware.GetUserId(c))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch notification preferences")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, preferences)
+}
+
+func (h *NotificationHandler) UpdateNotificationPreferences(c *gin.Context) {
+	var request UpdateNotificationPreferencesRequest
+	userId := middleware.GetUserId(c)
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	optional := services.OptionalNotificationTypes()
+	for _, preference := range request.Preferences {
+		if preference.Type != models.NotificationTypeAll && !slices.Contains(optional, preference.Type) {
+			utils.ValidationErrorResponse(c, "unknown or mandatory notification type: "+preference.Type)
+			return
+		}
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		for _, preference := range request.Preferences {
+			if err := services.SetNotificationPreference(tx, userId, preference.Type, preference.Channel, *preference.Enabled); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update notification preferences")
+		return
+	}
+
+	preferences, err := loadNotificationPreferences(userId)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch notification preferences")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, preferences)
+}
+
+// ConfirmUnsubscribe answers the unsubscribe link in an email without
+// changing anything, since mail scanners and link previews follow links.
+// Browsers get a page whose button posts to Unsubscribe, other clients a JSON
+// description of what the link turns off.
+func (h *NotificationHandler) ConfirmUnsubscribe(c *gin.Context) {
+	token := c.Query("token")
+	_, notificationType, channel, ok := h.unsubscribeTarget(c, token)
+	if !ok {
+		return
+	}
+
+	if c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEHTML {
+		c.Status(http.StatusOK)
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		_ = unsubscribePage.Execute(c.Writer, gin.H{
+			"Type":    notificationType,
+			"Channel": channel,
+			"Action":  "?token=" + url.QueryEscape(token),
+		})
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{
+		"message": "POST to this link to unsubscribe",
+		"type":    notificationType,
+		"channel": channel,
+	})
+}
+
+// Unsubscribe turns off the notification type named in a signed unsubscribe
+// token. It needs no login, so mail clients can use RFC 8058 one-click
+// unsubscribe.
+func (h *NotificationHandler) Unsubscribe(c *gin.Context) {
+	userID, notificationType, channel, ok := h.unsubscribeTarget(c, c.Query("token"))
+	if !ok {
+		return
+	}
+
+	if err := services.SetNotificationPreference(database.DB, userID, notificationType, channel, false); err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to unsubscribe")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{
+		"message": "You have been unsubscribed",
+		"type":    notificationType,
+		"channel": channel,
+	})
+}
+
+// unsubscribeTarget verifies an unsubscribe token and that its user still
+// exists, answering the request when either fails.
+func (h *NotificationHandler) unsubscribeTarget(c *gin.Context, token string) (userID uint, notificationType, channel string, ok bool) {
+	userID, notificationType, channel, ok = services.ParseUnsubscribeToken(h.cfg.JWTSecret, token)
+	if !ok {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid unsubscribe link")
+		return 0, "", "", false
+	}
+
+	if err := database.DB.First(&models.User{}, userID).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "User not found")
+		return 0, "", "", false
+	}
+
+	return userID, notificationType, channel, true
+}
+
+func loadNotificationPreferences(userID uint) ([]NotificationPreferenceResponse, error) {
+	var stored []models.NotificationPreference
+	if err := database.DB.Where("user_id = ?", userID).Find(&stored).Error; err != nil {
+		return nil, err
+	}
+
+	disabled := map[string]bool{}
+	for _, preference := range stored {
+		if !preference.Enabled {
+			disabled[preference.Type+":"+preference.Channel] = true
+		}
+	}
+
+	types := append([]string{models.NotificationTypeAll}, services.OptionalNotificationTypes()...)
+	preferences := make([]NotificationPreferenceResponse, len(types))
+	for i, notificationType := range types {
+		preferences[i] = NotificationPreferenceResponse{
+			Type:    notificationType,
+			Channel: models.NotificationChannelEmail,
+			Enabled: !disabled[notificationType+":"+models.NotificationChannelEmail],
+		}
+	}
+	return preferences, nil
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pick-cee/events-api/internal/database"
+	"github.com/pick-cee/events-api/internal/models"
+	"github.com/pick-cee/events-api/internal/services"
+	"github.com/pick-cee/events-api/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OutboxHandler struct{}
+
+func NewOutboxHandler() *OutboxHandler {
+	return &OutboxHandler{}
+}
+
+// ListOutboxMessages lists queued notifications, newest first, optionally
+// filtered by status and type.
+func (h *OutboxHandler) ListOutboxMessages(c *gin.Context) {
+	params := utils.GetPaginationParams(c.Request)
+
+	query := database.DB.Model(&models.OutboxMessage{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if notificationType := c.Query("type"); notificationType != "" {
+		query = query.Where("type = ?", notificationType)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to count outbox messages")
+		return
+	}
+
+	var messages []models.OutboxMessage
+	if err := query.Scopes(utils.Paginate(params)).Order("id DESC").Find(&messages).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch outbox messages")
+		return
+	}
+
+	for i := range messages {
+		messages[i].Payload = services.RedactPayload(messages[i].Payload)
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, utils.NewPaginationResponse(messages, total, params))
+}
+
+func (h *OutboxHandler) GetOutboxMessage(c *gin.Context) {
+	var message models.OutboxMessage
+	if err := database.DB.First(&message, c.Param("id")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Outbox message not found")
+		return
+	}
+
+	message.Payload = services.RedactPayload(message.Payload)
+	utils.SuccessResponse(c, http.StatusOK, message)
+}
+
+// ReplayOutboxMessage queues an undelivered message for immediate delivery
+// with a fresh set of attempts. Messages whose one-time links were scrubbed
+// cannot be replayed; the user has to request a new link.
+func (h *OutboxHandler) ReplayOutboxMessage(c *gin.Context) {
+	var message models.OutboxMessage
+	if err := database.DB.First(&message, c.Param("id")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Outbox message not found")
+		return
+	}
+
+	if message.Status == models.OutboxStatusSent {
+		utils.ErrorResponse(c, http.StatusConflict, "Outbox message already sent")
+		return
+	}
+
+	if message.Status == models.OutboxStatusSending {
+		utils.ErrorResponse(c, http.StatusConflict, "Outbox message is being delivered")
+		return
+	}
+
+	if message.Status == models.OutboxStatusSkipped {
+		utils.ErrorResponse(c, http.StatusConflict, "Outbox message was skipped")
+		return
+	}
+
+	if services.HasScrubbedPayload(message.Payload) {
+		utils.ErrorResponse(c, http.StatusConflict, "Outbox message links were scrubbed and cannot be sent again")
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// the delivery job may have picked the message up since it was read
+		result := tx.Model(&message).Where("status IN ?", replayableOutboxStatuses).Updates(replayedOutboxMessage())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errOutboxMessageChanged
+		}
+		return services.RequeueReminderDeliveries(tx, []uint{message.ID})
+	})
+
+	if errors.Is(err, errOutboxMessageChanged) {
+		utils.ErrorResponse(c, http.StatusConflict, "Outbox message changed status and was not replayed")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to replay outbox message")
+		return
+	}
+
+	message.Payload = services.RedactPayload(message.Payload)
+	utils.SuccessResponse(c, http.StatusOK, message)
+}
+
+// ReplayDeadOutboxMessages re-queues every dead message, optionally only those
+// of one type. Messages whose links were scrubbed are left dead, as
+// ReplayOutboxMessage refuses them.
+func (h *OutboxHandler) ReplayDeadOutboxMessages(c *gin.Context) {
+	var replayed int64
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "payload").
+			Where("status = ?", models.OutboxStatusDead)
+		if notificationType := c.Query("type"); notificationType != "" {
+			query = query.Where("type = ?", notificationType)
+		}
+
+		var dead []models.OutboxMessage
+		if err := query.Find(&dead).Error; err != nil {
+			return err
+		}
+
+		var ids []uint
+		for _, message := range dead {
+			if !services.HasScrubbedPayload(message.Payload) {
+				ids = append(ids, message.ID)
+			}
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		if err := services.RequeueReminderDeliveries(tx, ids); err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.OutboxMessage{}).Where("id IN ?", ids).Updates(replayedOutboxMessage())
+		replayed = result.RowsAffected
+		return result.Error
+	})
+
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to replay outbox messages")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{"replayed": replayed})
+}
+
+// errOutboxMessageChanged means a message was no longer pending or dead when
+// the replay wrote it.
+var errOutboxMessageChanged = errors.New("outbox message changed status")
+
+// replayableOutboxStatuses are the statuses a message can be replayed from.
+var replayableOutboxStatuses = []string{models.OutboxStatusPending, models.OutboxStatusDead}
+
+func replayedOutboxMessage() map[string]any {
+	return map[string]any{
+		"status":          models.OutboxStatusPending,
+		"attempts":        0,
+		"next_attempt_at": time.Now(),
+	}
+}
@@ -1,67 +1,136 @@
 package handlers
 
 import (
-	"fmt"
+	"errors"
+	"io"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/pick-cee/events-api/internal/cache"
+	"github.com/pick-cee/events-api/internal/config"
 	"github.com/pick-cee/events-api/internal/database"
 	"github.com/pick-cee/events-api/internal/middleware"
 	"github.com/pick-cee/events-api/internal/models"
 	"github.com/pick-cee/events-api/internal/services"
 	"github.com/pick-cee/events-api/internal/utils"
+	"gorm.io/gorm"
 )
 
 type RegistrationHandler struct {
+	cfg          *config.Config
 	emailService *services.EmailService
 }
 
-func NewRegistrationHandler(emailService *services.EmailService) *RegistrationHandler {
+func NewRegistrationHandler(cfg *config.Config, emailService *services.EmailService) *RegistrationHandler {
 	return &RegistrationHandler{
+		cfg:          cfg,
 		emailService: emailService,
 	}
 }
 
+type RegisterForEventRequest struct {
+	// Answers to the event's registration form, keyed by question id
+	Answers map[uint]any `json:"answers"`
+}
+
+// RegisterForEvent registers the user for an event. Private events need an
+// invitation with register access, either addressed to the user or carried by
+// the ?invite= token of its link; registering with a link accepts it. The body
+// is optional unless the event's form has required questions.
 func (h *RegistrationHandler) RegisterForEvent(c *gin.Context) {
 	eventId := c.Param("id")
 	userId := middleware.GetUserId(c)
 
-	// check if event exists
+	var user models.User
+	if err := database.DB.First(&user, userId).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "User not found")
+		return
+	}
+
 	var event models.Event
 	if err := database.DB.First(&event, eventId).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, "Event not found")
 		return
 	}
 
-	// check if already registered
-	var existingReg models.Registration
-	if err := database.DB.Where("user_id = ? AND event_id = ?", userId, eventId).First(&existingReg).Error; err == nil {
-		utils.ErrorResponse(c, http.StatusConflict, "Already registered for this event")
+	view, register := eventAccess(c, h.cfg.JWTSecret, &event)
+	if !view {
+		utils.ErrorResponse(c, http.StatusNotFound, "Event not found")
+		return
+	}
+	if !register {
+		utils.ErrorResponse(c, http.StatusForbidden, "Your invitation does not allow registering for this event")
 		return
 	}
 
-	var user models.User
-	if err := database.DB.First(&user, userId).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusNotFound, "User not found")
+	var request RegisterForEventRequest
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		utils.ValidationErrorResponse(c, err.Error())
 		return
 	}
 
-	// create registration
-	registration := models.Registration{
-		UserID:  userId,
-		EventID: event.ID,
+	var questions []models.RegistrationQuestion
+	if err := database.DB.Where("event_id = ?", event.ID).Scopes(orderByPosition).Find(&questions).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to register, try again")
+		return
 	}
 
-	if err := database.DB.Create(&registration).Error; err != nil {
+	answers, err := validateAnswers(questions, request.Answers)
+	if err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	invitation, linked := linkedInvitation(c, h.cfg.JWTSecret, event.ID)
+
+	var registration models.Registration
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		registration, err = createRegistration(tx, &event, event.ID, userId, answers)
+		if err != nil {
+			return err
+		}
+
+		if linked {
+			if err := acceptEventInvitation(tx, invitation, userId); err != nil && !errors.Is(err, errInvitationTaken) {
+				return err
+			}
+		}
+
+		if registration.Status != models.RegistrationStatusConfirmed {
+			return nil
+		}
+
+		if wants, err := services.WantsEmail(tx, user.ID, services.NotificationRegistrationConfirmed); err != nil || !wants {
+			return err
+		}
+
+		return h.emailService.SendEventRegistrarionSuccessEmail(tx, user.Email, user.Name, &event)
+	})
+
+	switch {
+	case errors.Is(err, errEventNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "Event not found")
+		return
+	case errors.Is(err, errAlreadyRegistered):
+		utils.ErrorResponse(c, http.StatusConflict, "Already registered for this event")
+		return
+	case errors.Is(err, errRegistrationRejected):
+		utils.ErrorResponse(c, http.StatusForbidden, "Your registration for this event was rejected")
+		return
+	case errors.Is(err, errEventCancelled):
+		utils.ErrorResponse(c, http.StatusConflict, "Event has been cancelled")
+		return
+	case err != nil:
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to register, try again")
 		return
 	}
 
-	database.DB.Preload("Event").Preload("User").Preload("Event.Creator").First(&registration, registration.ID)
+	invalidateRegistrations(c.Request.Context(), []uint{userId}, event.ID)
 
-	h.emailService.SendEventRegistrarionSuccessEmail(user.Email, user.Name, &event)
+	database.DB.Preload("Event").Preload("User").Preload("Event.Creator").First(&registration, registration.ID)
 
 	utils.SuccessResponse(c, http.StatusCreated, registration)
 }
@@ -70,34 +139,95 @@ func (h *RegistrationHandler) CancelRegistration(c *gin.Context) {
 	eventId := c.Param("id")
 	userId := middleware.GetUserId(c)
 
-	var registration models.Registration
-	if err := database.DB.Where("user_id = ? AND event_id = ?", userId, eventId).First(&registration).Error; err != nil {
+	var user models.User
+	if err := database.DB.First(&user, userId).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "User not found")
+		return
+	}
+
+	var event models.Event
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := cancelRegistration(tx, h.emailService, &event, eventId, userId); err != nil {
+			return err
+		}
+
+		if wants, err := services.WantsEmail(tx, user.ID, services.NotificationRegistrationCancelled); err != nil || !wants {
+			return err
+		}
+
+		return h.emailService.SendEventCancellationSuccessEmail(tx, user.Email, user.Name, &event)
+	})
+
+	switch {
+	case errors.Is(err, errEventNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "Event not found")
+		return
+	case errors.Is(err, errRegistrationNotFound):
 		utils.ErrorResponse(c, http.StatusNotFound, "Registration not found")
 		return
+	case err != nil:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to cancel registration")
+		return
 	}
 
+	// promoted users' lists embed the event, so its tag covers them
+	invalidateRegistrations(c.Request.Context(), []uint{userId}, event.ID)
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{"message": "Registration canceled successfully"})
+}
+
+// RemoveAttendee lets the event's owners and collaborators with attendee
+// permission cancel someone else's registration.
+func (h *RegistrationHandler) RemoveAttendee(c *gin.Context) {
 	var event models.Event
-	if err := database.DB.First(&event, eventId).Error; err != nil {
+	if err := database.DB.First(&event, c.Param("id")).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, "Event not found")
 		return
 	}
 
+	if !canManageEvent(c, &event, models.CollaboratorPermissionAttendees) {
+		utils.ErrorResponse(c, http.StatusForbidden, "You are not allowed to manage attendees of this event")
+		return
+	}
+
 	var user models.User
-	if err := database.DB.First(&user, userId).Error; err != nil {
+	if err := database.DB.First(&user, c.Param("userId")).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusNotFound, "User not found")
 		return
 	}
 
-	// delete registration
-	if err := database.DB.Delete(&registration).Error; err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to cancel registration")
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := cancelRegistration(tx, h.emailService, &event, event.ID, user.ID); err != nil {
+			return err
+		}
+
+		if wants, err := services.WantsEmail(tx, user.ID, services.NotificationRegistrationCancelled); err != nil || !wants {
+			return err
+		}
+
+		return h.emailService.SendEventCancellationSuccessEmail(tx, user.Email, user.Name, &event)
+	})
+
+	switch {
+	case errors.Is(err, errEventNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "Event not found")
+		return
+	case errors.Is(err, errRegistrationNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "Registration not found")
+		return
+	case err != nil:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to remove attendee")
 		return
 	}
 
-	h.emailService.SendEventCancellationSuccessEmail(user.Email, user.Name, &event)
-	utils.SuccessResponse(c, http.StatusOK, gin.H{"message": "Registration canceled successfully"})
+	invalidateRegistrations(c.Request.Context(), []uint{user.ID}, event.ID)
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{"message": "Attendee removed successfully"})
 }
 
+// GetEventAttendees lists an event's admitted attendees as far as the caller
+// may see them. See attendeeView for what each caller gets.
 func (h *RegistrationHandler) GetEventAttendees(c *gin.Context) {
 	eventId := c.Param("id")
 
@@ -108,25 +238,62 @@ func (h *RegistrationHandler) GetEventAttendees(c *gin.Context) {
 		return
 	}
 
-	// get all registrations with user info
+	if view, _ := eventAccess(c, h.cfg.JWTSecret, &event); !view {
+		utils.ErrorResponse(c, http.StatusNotFound, "Event does not exists")
+		return
+	}
+
+	view := attendeeView(c, &event)
+	response := AttendeeListResponse{View: view, Attendees: []AttendeeResponse{}}
+
+	var counts []struct {
+		Status string
+		Total  int64
+	}
+	if err := database.DB.Model(&models.Registration{}).Select("status, COUNT(*) AS total").
+		Where("event_id = ?", event.ID).Scopes(admittedRegistrations).Group("status").Scan(&counts).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch attendees")
+		return
+	}
+	for _, count := range counts {
+		if count.Status == models.RegistrationStatusConfirmed {
+			response.Count = count.Total
+		} else {
+			response.Waitlisted = count.Total
+		}
+	}
+
+	// applicants awaiting or refused approval are listed to organizers
+	// through ListRegistrations
+	query := database.DB.Where("event_id = ?", event.ID).Scopes(admittedRegistrations)
+	if view == attendeeViewPublic {
+		query = query.Scopes(listedAttendees)
+	}
+
 	var registrations []models.Registration
-	if err := database.DB.Where("event_id = ?", event.ID).Preload("User").Preload("Event.Creator").Find(&registrations).Error; err != nil {
+	if err := query.Preload("User").Order("created_at ASC, id ASC").Find(&registrations).Error; err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch attendees")
 		return
 	}
 
-	utils.SuccessResponse(c, http.StatusOK, registrations)
+	viewerID := middleware.GetUserId(c)
+	for i := range registrations {
+		response.Attendees = append(response.Attendees, newAttendeeResponse(&registrations[i], view, viewerID))
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, response)
 }
 
 func (h *RegistrationHandler) GetMyRegistrations(c *gin.Context) {
 	userId := middleware.GetUserId(c)
 
-	cacheKey := fmt.Sprintf("event_registrations:userId=%v", userId)
+	cacheKey := userRegistrationsCacheKey(userId)
 	ctx := c.Request.Context()
 
 	var cached []models.Registration
 	if err := cache.Get(ctx, cacheKey, &cached); err == nil {
 		utils.SuccessResponse(c, http.StatusOK, cached)
+		return
 	}
 
 	var registrations []models.Registration
@@ -135,7 +302,12 @@ func (h *RegistrationHandler) GetMyRegistrations(c *gin.Context) {
 		return
 	}
 
-	_ = cache.Set(ctx, cacheKey, registrations, 5*time.Minute)
+	tags := []string{userRegistrationsTag(userId)}
+	for _, registration := range registrations {
+		tags = append(tags, eventTag(registration.EventID))
+	}
+
+	_ = cache.SetWithTags(ctx, cacheKey, registrations, 5*time.Minute, tags...)
 
 	utils.SuccessResponse(c, http.StatusOK, registrations)
 }
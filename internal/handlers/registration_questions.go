@@ -0,0 +1,286 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pick-cee/events-api/internal/database"
+	"github.com/pick-cee/events-api/internal/models"
+	"github.com/pick-cee/events-api/internal/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxQuestions bounds the size of an event's registration form
+	maxQuestions = 50
+	// maxQuestionOptions bounds the choices of a choice question
+	maxQuestionOptions = 50
+	// maxTextAnswer bounds the length of a text answer, in characters
+	maxTextAnswer = 2000
+)
+
+// errUnknownQuestion is returned for a question id that is not on the event's
+// form.
+var errUnknownQuestion = errors.New("unknown question id")
+
+type RegistrationQuestionRequest struct {
+	ID       uint     `json:"id"` // keeps an existing question when updating the form
+	Label    string   `json:"label" binding:"required,max=200"`
+	Type     string   `json:"type" binding:"required,oneof=text single_choice multi_choice number boolean"`
+	Options  []string `json:"options"`
+	Required bool     `json:"required"`
+}
+
+type UpdateRegistrationQuestionsRequest struct {
+	Questions []RegistrationQuestionRequest `json:"questions" binding:"required,dive"`
+}
+
+// GetRegistrationQuestions returns an event's registration form to anyone who
+// can see the event.
+func (h *EventHandler) GetRegistrationQuestions(c *gin.Context) {
+	var event models.Event
+	if err := database.DB.Preload("Questions", orderByPosition).First(&event, c.Param("id")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	if view, _ := eventAccess(c, h.cfg.JWTSecret, &event); !view {
+		utils.ErrorResponse(c, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, event.Questions)
+}
+
+// UpdateRegistrationQuestions replaces an event's registration form. Questions
+// sent with their id are updated in place, questions without one are added and
+// questions left out are removed. Answers already given are kept as they were.
+func (h *EventHandler) UpdateRegistrationQuestions(c *gin.Context) {
+	var event models.Event
+	if err := database.DB.First(&event, c.Param("id")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	if !canManageEvent(c, &event, models.CollaboratorPermissionEdit) {
+		utils.ErrorResponse(c, http.StatusForbidden, "You can only manage the registration form of your own events")
+		return
+	}
+
+	var request UpdateRegistrationQuestionsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	questions, err := registrationForm(request.Questions)
+	if err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var existing []uint
+		if err := tx.Model(&models.RegistrationQuestion{}).Where("event_id = ?", event.ID).Pluck("id", &existing).Error; err != nil {
+			return err
+		}
+
+		kept := []uint{}
+		for i := range questions {
+			questions[i].EventID = event.ID
+			if questions[i].ID == 0 {
+				continue
+			}
+			if !slices.Contains(existing, questions[i].ID) {
+				return fmt.Errorf("%w: %d", errUnknownQuestion, questions[i].ID)
+			}
+			kept = append(kept, questions[i].ID)
+		}
+
+		removed := tx.Where("event_id = ?", event.ID)
+		if len(kept) > 0 {
+			removed = removed.Where("id NOT IN ?", kept)
+		}
+		if err := removed.Delete(&models.RegistrationQuestion{}).Error; err != nil {
+			return err
+		}
+
+		for i := range questions {
+			if err := tx.Save(&questions[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	switch {
+	case errors.Is(err, errUnknownQuestion):
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	case err != nil:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update registration form")
+		return
+	}
+
+	invalidateEvents(c.Request.Context(), event.ID)
+
+	utils.SuccessResponse(c, http.StatusOK, questions)
+}
+
+// registrationForm validates requested questions and turns them into a form,
+// numbered in the order given.
+func registrationForm(requests []RegistrationQuestionRequest) ([]models.RegistrationQuestion, error) {
+	if len(requests) > maxQuestions {
+		return nil, fmt.Errorf("a registration form can have at most %d questions", maxQuestions)
+	}
+
+	questions := make([]models.RegistrationQuestion, len(requests))
+	for i, request := range requests {
+		question := models.RegistrationQuestion{
+			ID:       request.ID,
+			Label:    strings.TrimSpace(request.Label),
+			Type:     request.Type,
+			Required: request.Required,
+			Position: i,
+		}
+
+		if question.Label == "" {
+			return nil, errors.New("every question needs a label")
+		}
+
+		if !question.IsChoice() {
+			if len(request.Options) > 0 {
+				return nil, fmt.Errorf("question %q cannot have options", question.Label)
+			}
+			questions[i] = question
+			continue
+		}
+
+		for _, option := range request.Options {
+			option = strings.TrimSpace(option)
+			if option == "" || slices.Contains(question.Options, option) {
+				return nil, fmt.Errorf("options of question %q must be unique and not empty", question.Label)
+			}
+			question.Options = append(question.Options, option)
+		}
+
+		if len(question.Options) < 2 || len(question.Options) > maxQuestionOptions {
+			return nil, fmt.Errorf("question %q needs between 2 and %d options", question.Label, maxQuestionOptions)
+		}
+		questions[i] = question
+	}
+
+	return questions, nil
+}
+
+// validateAnswers checks answers, keyed by question id, against an event's
+// form and returns them in form order. Blank answers count as missing.
+func validateAnswers(questions []models.RegistrationQuestion, answers map[uint]any) ([]models.RegistrationAnswer, error) {
+	for id := range answers {
+		if !slices.ContainsFunc(questions, func(q models.RegistrationQuestion) bool { return q.ID == id }) {
+			return nil, fmt.Errorf("%w: %d", errUnknownQuestion, id)
+		}
+	}
+
+	var validated []models.RegistrationAnswer
+	for _, question := range questions {
+		value, err := answerValue(&question, answers[question.ID])
+		if err != nil {
+			return nil, err
+		}
+
+		if value == nil {
+			if question.Required {
+				return nil, fmt.Errorf("question %q is required", question.Label)
+			}
+			continue
+		}
+
+		validated = append(validated, models.RegistrationAnswer{
+			QuestionID: question.ID,
+			Label:      question.Label,
+			Value:      value,
+		})
+	}
+
+	return validated, nil
+}
+
+// answerValue checks a decoded JSON answer against the question's type and
+// normalizes it, returning nil for a blank answer.
+func answerValue(question *models.RegistrationQuestion, answer any) (any, error) {
+	if answer == nil {
+		return nil, nil
+	}
+
+	switch question.Type {
+	case models.QuestionTypeText:
+		text, ok := answer.(string)
+		if !ok {
+			return nil, fmt.Errorf("answer to %q must be text", question.Label)
+		}
+		text = strings.TrimSpace(text)
+		if len([]rune(text)) > maxTextAnswer {
+			return nil, fmt.Errorf("answer to %q must be at most %d characters", question.Label, maxTextAnswer)
+		}
+		if text == "" {
+			return nil, nil
+		}
+		return text, nil
+
+	case models.QuestionTypeNumber:
+		number, ok := answer.(float64)
+		if !ok {
+			return nil, fmt.Errorf("answer to %q must be a number", question.Label)
+		}
+		return number, nil
+
+	case models.QuestionTypeBoolean:
+		value, ok := answer.(bool)
+		if !ok {
+			return nil, fmt.Errorf("answer to %q must be true or false", question.Label)
+		}
+		return value, nil
+
+	case models.QuestionTypeSingleChoice:
+		choice, ok := answer.(string)
+		if !ok || (choice != "" && !slices.Contains(question.Options, choice)) {
+			return nil, fmt.Errorf("answer to %q must be one of its options", question.Label)
+		}
+		if choice == "" {
+			return nil, nil
+		}
+		return choice, nil
+
+	case models.QuestionTypeMultiChoice:
+		list, ok := answer.([]any)
+		if !ok {
+			return nil, fmt.Errorf("answer to %q must be a list of its options", question.Label)
+		}
+
+		choices := []string{}
+		for _, item := range list {
+			choice, ok := item.(string)
+			if !ok || !slices.Contains(question.Options, choice) {
+				return nil, fmt.Errorf("answer to %q must be a list of its options", question.Label)
+			}
+			if !slices.Contains(choices, choice) {
+				choices = append(choices, choice)
+			}
+		}
+		if len(choices) == 0 {
+			return nil, nil
+		}
+		return choices, nil
+	}
+
+	return nil, fmt.Errorf("question %q has unknown type %q", question.Label, question.Type)
+}
+
+func orderByPosition(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC, id ASC")
+}
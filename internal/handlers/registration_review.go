@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pick-cee/events-api/internal/database"
+	"github.com/pick-cee/events-api/internal/middleware"
+	"github.com/pick-cee/events-api/internal/models"
+	"github.com/pick-cee/events-api/internal/services"
+	"github.com/pick-cee/events-api/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReviewRegistrationsRequest struct {
+	RegistrationIDs []uint `json:"registration_ids" binding:"required,min=1,max=100"`
+	Message         string `json:"message" binding:"max=2000"`
+}
+
+// ReviewRegistrationsResponse reports the outcome of a bulk decision.
+// Skipped registrations were not pending, or do not belong to the event.
+type ReviewRegistrationsResponse struct {
+	Reviewed []models.Registration `json:"reviewed"`
+	Skipped  []uint                `json:"skipped"`
+}
+
+// ListRegistrations pages through every registration of an event, oldest
+// first, for organizers reviewing applications. ?status=pending lists those
+// awaiting a decision.
+func (h *RegistrationHandler) ListRegistrations(c *gin.Context) {
+	var event models.Event
+	if err := database.DB.First(&event, c.Param("id")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	if !canManageEvent(c, &event, models.CollaboratorPermissionAttendees) {
+		utils.ErrorResponse(c, http.StatusForbidden, "You are not allowed to manage attendees of this event")
+		return
+	}
+
+	params := utils.GetPaginationParams(c.Request)
+
+	query := database.DB.Model(&models.Registration{}).Where("event_id = ?", event.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to count registrations")
+		return
+	}
+
+	var registrations []models.Registration
+	if err := query.Scopes(utils.Paginate(params)).Preload("User").Order("created_at ASC, id ASC").Find(&registrations).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch registrations")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, utils.NewPaginationResponse(registrations, total, params))
+}
+
+// ApproveRegistrations admits pending registrations, oldest first. Each gets
+// a seat while any are free and a waitlist place otherwise.
+func (h *RegistrationHandler) ApproveRegistrations(c *gin.Context) {
+	h.reviewRegistrations(c, true)
+}
+
+func (h *RegistrationHandler) RejectRegistrations(c *gin.Context) {
+	h.reviewRegistrations(c, false)
+}
+
+// reviewRegistrations records the organizer's decision on pending
+// registrations and emails it, with the optional message, to each applicant
+// who wants it.
+func (h *RegistrationHandler) reviewRegistrations(c *gin.Context, approve bool) {
+	var request ReviewRegistrationsRequest
+
+	var event models.Event
+	if err := database.DB.First(&event, c.Param("id")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	if !canManageEvent(c, &event, models.CollaboratorPermissionAttendees) {
+		utils.ErrorResponse(c, http.StatusForbidden, "You are not allowed to manage attendees of this event")
+		return
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	reviewerID := middleware.GetUserId(c)
+	notificationType := services.NotificationRegistrationRejected
+	if approve {
+		notificationType = services.NotificationRegistrationApproved
+	}
+
+	reviewed := []models.Registration{}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// lock the event so approvals count seats one at a time
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&event, event.ID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Preload("User").
+			Where("event_id = ? AND id IN ? AND status = ?", event.ID, request.RegistrationIDs, models.RegistrationStatusPending).
+			Order("created_at ASC, id ASC").Find(&reviewed).Error; err != nil {
+			return err
+		}
+
+		userIDs := make([]uint, len(reviewed))
+		for i, registration := range reviewed {
+			userIDs[i] = registration.UserID
+		}
+
+		optedOut, err := services.OptedOutUsers(tx, notificationType, userIDs)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for i := range reviewed {
+			registration := &reviewed[i]
+
+			status := models.RegistrationStatusRejected
+			if approve {
+				if status, err = nextRegistrationStatus(tx, &event); err != nil {
+					return err
+				}
+			}
+
+			registration.Status = status
+			registration.ReviewedByID = &reviewerID
+			registration.ReviewedAt = &now
+			registration.ReviewMessage = request.Message
+
+			if err := tx.Model(registration).Updates(map[string]any{
+				"status":         registration.Status,
+				"reviewed_by_id": reviewerID,
+				"reviewed_at":    now,
+				"review_message": request.Message,
+			}).Error; err != nil {
+				return err
+			}
+
+			if optedOut[registration.UserID] {
+				continue
+			}
+
+			user := registration.User
+			if approve {
+				err = h.emailService.SendRegistrationApprovedEmail(tx, user.Email, user.Name, &event, status == models.RegistrationStatusWaitlisted, request.Message)
+			} else {
+				err = h.emailService.SendRegistrationRejectedEmail(tx, user.Email, user.Name, &event, request.Message)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to review registrations")
+		return
+	}
+
+	response := ReviewRegistrationsResponse{Reviewed: reviewed, Skipped: []uint{}}
+	seen := make(map[uint]bool, len(request.RegistrationIDs))
+	userIDs := make([]uint, len(reviewed))
+	for i, registration := range reviewed {
+		seen[registration.ID] = true
+		userIDs[i] = registration.UserID
+	}
+	for _, id := range request.RegistrationIDs {
+		if !seen[id] {
+			seen[id] = true
+			response.Skipped = append(response.Skipped, id)
+		}
+	}
+
+	invalidateRegistrations(c.Request.Context(), userIDs, event.ID)
+
+	utils.SuccessResponse(c, http.StatusOK, response)
+}
+
+// admittedRegistrations narrows a query to registrations holding a seat or a
+// waitlist place, leaving out pending and rejected applications.
+func admittedRegistrations(db *gorm.DB) *gorm.DB {
+	return db.Where("status IN ?", []string{models.RegistrationStatusConfirmed, models.RegistrationStatusWaitlisted})
+}
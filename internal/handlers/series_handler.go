@@ -0,0 +1,681 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pick-cee/events-api/internal/config"
+	"github.com/pick-cee/events-api/internal/database"
+	"github.com/pick-cee/events-api/internal/middleware"
+	"github.com/pick-cee/events-api/internal/models"
+	"github.com/pick-cee/events-api/internal/recurrence"
+	"github.com/pick-cee/events-api/internal/services"
+	"github.com/pick-cee/events-api/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// upper bound on occurrences created for a single series
+	maxSeriesOccurrences = 366
+
+	scopeThis      = "this"
+	scopeFollowing = "following"
+)
+
+// errNothingToRegister means no upcoming occurrence of a series takes the
+// user's registration: none is left, or each is cancelled, needs answers or
+// rejected the user.
+var errNothingToRegister = errors.New("nothing to register for")
+
+type SeriesHandler struct {
+	cfg          *config.Config
+	emailService *services.EmailService
+}
+
+func NewSeriesHandler(cfg *config.Config, emailService *services.EmailService) *SeriesHandler {
+	return &SeriesHandler{
+		cfg:          cfg,
+		emailService: emailService,
+	}
+}
+
+// Request/Response DTOs
+type CreateSeriesRequest struct {
+	Title            string      `json:"title" binding:"required"`
+	Description      string      `json:"description"`
+	Location         string      `json:"location" binding:"required"`
+	StartTime        time.Time   `json:"start_time" binding:"required"`
+	EndTime          time.Time   `json:"end_time"`
+	DurationMinutes  int         `json:"duration_minutes" binding:"omitempty,min=1"`
+	TimeZone         string      `json:"time_zone"`
+	Capacity         int         `json:"capacity" binding:"min=0"`
+	Visibility       string      `json:"visibility" binding:"omitempty,oneof=public unlisted private"`
+	RequiresApproval bool        `json:"requires_approval"`
+	RRule            string      `json:"rrule" binding:"required"`
+	ExDates          []time.Time `json:"exdates"`
+}
+
+func (h *SeriesHandler) CreateSeries(c *gin.Context) {
+	var request CreateSeriesRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	rule, err := recurrence.Parse(request.RRule)
+	if err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	// validate the first occurrence's schedule the same way as a single event
+	first := models.Event{DateTime: request.StartTime}
+	if err := setEventTimes(&first, request.EndTime, request.DurationMinutes, request.TimeZone); err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	series := models.EventSeries{
+		Title:            request.Title,
+		Description:      request.Description,
+		Location:         request.Location,
+		Capacity:         request.Capacity,
+		Visibility:       request.Visibility,
+		RequiresApproval: request.RequiresApproval,
+		StartTime:        first.DateTime,
+		EndTime:          first.EndTime,
+		TimeZone:         first.TimeZone,
+		RRule:            rule.String(),
+		ExDates:          request.ExDates,
+		CreatorID:        middleware.GetUserId(c),
+	}
+
+	if series.Visibility == "" {
+		series.Visibility = models.EventVisibilityPublic
+	}
+
+	// expand in the series' zone so occurrences keep their wall-clock time
+	// across DST. Bounded rules are created in full, so one running past the
+	// cap is refused rather than cut short
+	starts := rule.Occurrences(series.LocalStart(), series.ExDates, maxSeriesOccurrences+1, seriesHorizon(rule))
+	if len(starts) == 0 {
+		utils.ValidationErrorResponse(c, "rrule does not produce any occurrences")
+		return
+	}
+	if len(starts) > maxSeriesOccurrences {
+		if rule.Bounded() {
+			utils.ValidationErrorResponse(c, fmt.Sprintf("rrule produces more than %d occurrences", maxSeriesOccurrences))
+			return
+		}
+		starts = starts[:maxSeriesOccurrences]
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&series).Error; err != nil {
+			return err
+		}
+
+		occurrences := make([]models.Event, len(starts))
+		for i, start := range starts {
+			occurrences[i] = series.NewOccurrence(start)
+		}
+
+		return tx.Create(&occurrences).Error
+	})
+
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to create event series")
+		return
+	}
+
+	invalidateEventLists(c.Request.Context())
+
+	database.DB.Preload("Creator").Preload("Occurrences", orderByDateTime).First(&series, series.ID)
+
+	utils.SuccessResponse(c, http.StatusCreated, series)
+}
+
+// GetSeriesById returns a series with the occurrences the caller may see. A
+// private series is hidden from those who cannot see any of its occurrences.
+func (h *SeriesHandler) GetSeriesById(c *gin.Context) {
+	id := c.Param("id")
+
+	var series models.EventSeries
+	if err := database.DB.Preload("Creator").Preload("Occurrences", orderByDateTime).First(&series, id).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Event series not found")
+		return
+	}
+
+	if !isEventOwner(c, series.CreatorID) {
+		visible := []models.Event{}
+		for _, occurrence := range series.Occurrences {
+			if view, _ := eventAccess(c, h.cfg.JWTSecret, &occurrence); view {
+				visible = append(visible, occurrence)
+			}
+		}
+
+		if series.Visibility == models.EventVisibilityPrivate && len(visible) == 0 {
+			utils.ErrorResponse(c, http.StatusNotFound, "Event series not found")
+			return
+		}
+		series.Occurrences = visible
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, series)
+}
+
+// UpdateOccurrence edits a single occurrence (scope=this, the default) or the
+// occurrence and every later one (scope=following). The latter splits the
+// series in two, as RFC 5545 clients do.
+func (h *SeriesHandler) UpdateOccurrence(c *gin.Context) {
+	var request UpdateEventRequest
+	scope := c.DefaultQuery("scope", scopeThis)
+
+	series, occurrence, ok := h.loadOccurrence(c)
+	if !ok {
+		return
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	before := *occurrence
+	preview := *occurrence
+	if err := applyEventUpdate(&preview, &request); err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	var err error
+	updated := []uint{occurrence.ID}
+
+	switch scope {
+	case scopeThis:
+		err = updateOccurrences(database.DB, h.emailService, []models.Event{*occurrence}, &request, nil, 0)
+	case scopeFollowing:
+		updated, err = h.splitSeries(series, occurrence, &request)
+	default:
+		utils.ValidationErrorResponse(c, "scope must be 'this' or 'following'")
+		return
+	}
+
+	if errors.Is(err, errEventCancelled) {
+		utils.ErrorResponse(c, http.StatusConflict, "Cancelled events cannot be updated")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to update event series")
+		return
+	}
+
+	database.DB.Preload("Creator").First(occurrence, occurrence.ID)
+
+	// later occurrences of a split get the same changes as this one
+	invalidateEvents(c.Request.Context(), updated...)
+	if movesInLists(changedEventColumns(&before, occurrence)) {
+		invalidateEventLists(c.Request.Context())
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, occurrence)
+}
+
+// CancelOccurrence calls off a single occurrence (scope=this, the default)
+// or ends the series at the occurrence (scope=following). Occurrences are
+// cancelled like single events, so they stay visible and their registrants
+// are emailed; the optional body takes the reason.
+func (h *SeriesHandler) CancelOccurrence(c *gin.Context) {
+	scope := c.DefaultQuery("scope", scopeThis)
+
+	series, occurrence, ok := h.loadOccurrence(c)
+	if !ok {
+		return
+	}
+
+	var request CancelEventRequest
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	recurrenceID := occurrenceStart(occurrence)
+	var canceled, registrants []uint
+
+	var err error
+	switch scope {
+	case scopeThis:
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			var err error
+			if registrants, err = cancelEvent(tx, h.emailService, occurrence, request.Reason); err != nil {
+				return err
+			}
+			canceled = []uint{occurrence.ID}
+
+			series.ExDates = append(series.ExDates, recurrenceID)
+			return tx.Save(series).Error
+		})
+	case scopeFollowing:
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			var following []models.Event
+			if err := tx.Where("series_id = ? AND recurrence_id >= ?", series.ID, recurrenceID).
+				Order("recurrence_id ASC").Find(&following).Error; err != nil {
+				return err
+			}
+
+			// occurrences already cancelled or over are left as they are
+			for i := range following {
+				users, err := cancelEvent(tx, h.emailService, &following[i], request.Reason)
+				if errors.Is(err, errEventCancelled) || errors.Is(err, errEventEnded) {
+					continue
+				}
+				if err != nil {
+					return err
+				}
+				canceled = append(canceled, following[i].ID)
+				registrants = append(registrants, users...)
+			}
+
+			return endSeriesBefore(tx, series, recurrenceID)
+		})
+	default:
+		utils.ValidationErrorResponse(c, "scope must be 'this' or 'following'")
+		return
+	}
+
+	switch {
+	case errors.Is(err, errEventCancelled):
+		utils.ErrorResponse(c, http.StatusConflict, "Occurrence is already cancelled")
+		return
+	case errors.Is(err, errEventEnded):
+		utils.ErrorResponse(c, http.StatusConflict, "Occurrence has already ended")
+		return
+	case err != nil:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to cancel occurrence")
+		return
+	}
+
+	invalidateRegistrations(c.Request.Context(), registrants, canceled...)
+
+	utils.SuccessResponse(c, http.StatusOK, gin.H{"message": "Occurrence canceled successfully"})
+}
+
+// RegisterForSeries registers the user for every upcoming occurrence of a
+// series. Occurrences the user is already registered for are left untouched,
+// and private ones the user is not invited to are skipped, as are those whose
+// registration form has required questions.
+func (h *SeriesHandler) RegisterForSeries(c *gin.Context) {
+	id := c.Param("id")
+	userId := middleware.GetUserId(c)
+
+	var user models.User
+	if err := database.DB.First(&user, userId).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "User not found")
+		return
+	}
+
+	var series models.EventSeries
+	if err := database.DB.First(&series, id).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Event series not found")
+		return
+	}
+
+	var upcoming []models.Event
+	if err := database.DB.Where("series_id = ? AND date_time > ?", series.ID, time.Now()).
+		Order("date_time ASC").Find(&upcoming).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch occurrences")
+		return
+	}
+
+	upcomingIDs := make([]uint, len(upcoming))
+	for i, occurrence := range upcoming {
+		upcomingIDs[i] = occurrence.ID
+	}
+
+	// required answers cannot be given for a whole series at once
+	var withForm []uint
+	if err := database.DB.Model(&models.RegistrationQuestion{}).Where("event_id IN ? AND required", upcomingIDs).
+		Distinct().Pluck("event_id", &withForm).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to fetch occurrences")
+		return
+	}
+
+	allowed := []models.Event{}
+	for _, occurrence := range upcoming {
+		if slices.Contains(withForm, occurrence.ID) {
+			continue
+		}
+		if _, register := eventAccess(c, h.cfg.JWTSecret, &occurrence); register {
+			allowed = append(allowed, occurrence)
+		}
+	}
+
+	if series.Visibility == models.EventVisibilityPrivate && len(allowed) == 0 {
+		utils.ErrorResponse(c, http.StatusNotFound, "Event series not found")
+		return
+	}
+
+	// one transaction for the whole series, so a failure leaves no partial
+	// registration behind. Occurrences are locked in start order.
+	registrations := []models.Registration{}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		registered := 0
+		for _, occurrence := range allowed {
+			var event models.Event
+			registration, err := createRegistration(tx, &event, occurrence.ID, userId, nil)
+			if errors.Is(err, errAlreadyRegistered) {
+				registered++
+				continue
+			}
+			if errors.Is(err, errRegistrationRejected) || errors.Is(err, errEventCancelled) || errors.Is(err, errEventNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			registration.Event = event
+			registrations = append(registrations, registration)
+		}
+
+		switch {
+		case len(registrations) > 0:
+		case registered > 0 && registered == len(allowed):
+			return errAlreadyRegistered
+		default:
+			return errNothingToRegister
+		}
+
+		if wants, err := services.WantsEmail(tx, user.ID, services.NotificationSeriesRegistration); err != nil || !wants {
+			return err
+		}
+		return h.emailService.SendSeriesRegistrationSuccessEmail(tx, user.Email, user.Name, &series, len(registrations))
+	})
+
+	if errors.Is(err, errAlreadyRegistered) {
+		utils.ErrorResponse(c, http.StatusConflict, "Already registered for every upcoming occurrence")
+		return
+	}
+	if errors.Is(err, errNothingToRegister) {
+		utils.ErrorResponse(c, http.StatusUnprocessableEntity, "No upcoming occurrence of this series is open for registration")
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to register, try again")
+		return
+	}
+
+	eventIDs := make([]uint, len(registrations))
+	for i, registration := range registrations {
+		eventIDs[i] = registration.EventID
+	}
+	invalidateRegistrations(c.Request.Context(), []uint{userId}, eventIDs...)
+
+	utils.SuccessResponse(c, http.StatusCreated, registrations)
+}
+
+// loadOccurrence fetches the series and occurrence named in the route and
+// checks that the caller may manage the series.
+func (h *SeriesHandler) loadOccurrence(c *gin.Context) (*models.EventSeries, *models.Event, bool) {
+	var series models.EventSeries
+	if err := database.DB.First(&series, c.Param("id")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Event series not found")
+		return nil, nil, false
+	}
+
+	if !isEventOwner(c, series.CreatorID) {
+		utils.ErrorResponse(c, http.StatusForbidden, "You can only update your own events")
+		return nil, nil, false
+	}
+
+	var occurrence models.Event
+	if err := database.DB.Where("series_id = ?", series.ID).First(&occurrence, c.Param("eventId")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Occurrence not found")
+		return nil, nil, false
+	}
+
+	return &series, &occurrence, true
+}
+
+// splitSeries ends the original series just before the occurrence and moves the
+// occurrence and every later one into a new series carrying the changes. It
+// returns the ids of the occurrences it changed.
+func (h *SeriesHandler) splitSeries(series *models.EventSeries, occurrence *models.Event, request *UpdateEventRequest) ([]uint, error) {
+	rule, err := recurrence.Parse(series.RRule)
+	if err != nil {
+		return nil, err
+	}
+
+	recurrenceID := occurrenceStart(occurrence)
+
+	// the edited occurrence defines the new start offset and duration
+	edited := *occurrence
+	if err := applyEventUpdate(&edited, request); err != nil {
+		return nil, err
+	}
+	if request.Capacity != nil {
+		edited.Capacity = *request.Capacity
+	}
+	shift := edited.DateTime.Sub(occurrence.DateTime)
+
+	var updated []uint
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var following []models.Event
+		if err := tx.Where("series_id = ? AND recurrence_id >= ?", series.ID, recurrenceID).
+			Order("recurrence_id ASC").Find(&following).Error; err != nil {
+			return err
+		}
+
+		for _, event := range following {
+			updated = append(updated, event.ID)
+		}
+
+		target := series
+
+		// editing from the first occurrence changes the whole series in place
+		if recurrenceID.After(series.StartTime) {
+			// keep the remaining run length when the rule was bounded by COUNT
+			if rule.Count > 0 {
+				original := rule.Occurrences(series.LocalStart(), nil, recurrence.MaxCount, seriesHorizon(rule))
+				rule.Count = 0
+				if len(original) > 0 {
+					rule.Until = original[len(original)-1]
+				}
+			}
+
+			var exdates []time.Time
+			for _, exdate := range series.ExDates {
+				if !exdate.Before(recurrenceID) {
+					exdates = append(exdates, exdate)
+				}
+			}
+
+			target = &models.EventSeries{
+				Title:       series.Title,
+				Description: series.Description,
+				Location:    series.Location,
+				Capacity:    series.Capacity,
+				StartTime:   recurrenceID,
+				TimeZone:    series.TimeZone,
+				RRule:       rule.String(),
+				ExDates:     exdates,
+				CreatorID:   series.CreatorID,
+			}
+
+			if err := endSeriesBefore(tx, series, recurrenceID); err != nil {
+				return err
+			}
+		}
+
+		applySeriesChanges(target, &edited, shift)
+		if shift != 0 {
+			// occurrences moved to another day keep falling on it
+			rule = rule.ShiftDays(calendarDays(occurrence.LocalStart(), edited.LocalStart()))
+			if !rule.Until.IsZero() {
+				rule.Until = rule.Until.Add(shift)
+			}
+			target.RRule = rule.String()
+		}
+
+		if err := tx.Save(target).Error; err != nil {
+			return err
+		}
+
+		return updateOccurrences(tx, h.emailService, following, request, target, shift)
+	})
+
+	return updated, err
+}
+
+// endSeriesBefore truncates a series so that its last occurrence starts before t.
+func endSeriesBefore(tx *gorm.DB, series *models.EventSeries, t time.Time) error {
+	if !t.After(series.StartTime) {
+		return tx.Delete(series).Error
+	}
+
+	rule, err := recurrence.Parse(series.RRule)
+	if err != nil {
+		return err
+	}
+
+	rule.Count = 0
+	rule.Until = t.Add(-time.Second)
+	series.RRule = rule.String()
+
+	var exdates []time.Time
+	for _, exdate := range series.ExDates {
+		if exdate.Before(t) {
+			exdates = append(exdates, exdate)
+		}
+	}
+	series.ExDates = exdates
+
+	return tx.Save(series).Error
+}
+
+// updateOccurrences applies an update request to a set of occurrences. When
+// moveTo is set the occurrences are moved to that series, their start times
+// shifted by shift and their duration taken from the series; otherwise the
+// requested times are applied as is. Cancelled occurrences are not edited:
+// a single one is refused with errEventCancelled, and moved ones only change
+// series.
+func updateOccurrences(tx *gorm.DB, emailService *services.EmailService, occurrences []models.Event, request *UpdateEventRequest, moveTo *models.EventSeries, shift time.Duration) error {
+	return tx.Transaction(func(tx *gorm.DB) error {
+		for i := range occurrences {
+			// re-read under lock so a concurrent cancellation or edit is not
+			// overwritten, and seat counting does not race with registrations
+			event := &occurrences[i]
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(event, event.ID).Error; err != nil {
+				return err
+			}
+			previous := *event
+
+			if event.IsCancelled() {
+				if moveTo == nil {
+					return errEventCancelled
+				}
+				recurrenceID := occurrenceStart(event).Add(shift)
+				if err := tx.Model(event).Updates(map[string]any{"series_id": moveTo.ID, "recurrence_id": recurrenceID}).Error; err != nil {
+					return err
+				}
+				continue
+			}
+
+			if moveTo == nil {
+				if err := applyEventUpdate(event, request); err != nil {
+					return err
+				}
+			} else {
+				recurrenceID := occurrenceStart(event).Add(shift)
+				event.Title = moveTo.Title
+				event.Description = moveTo.Description
+				event.Location = moveTo.Location
+				event.Visibility = moveTo.Visibility
+				event.RequiresApproval = moveTo.RequiresApproval
+				event.TimeZone = moveTo.TimeZone
+				event.SeriesID = &moveTo.ID
+				event.RecurrenceID = &recurrenceID
+				event.DateTime = event.DateTime.Add(shift)
+				event.EndTime = event.DateTime.Add(moveTo.Duration())
+			}
+
+			if request.Capacity != nil {
+				event.Capacity = *request.Capacity
+			}
+
+			if columns := changedEventColumns(&previous, event); len(columns) > 0 {
+				if err := tx.Model(event).Select(append(columns, "updated_at")).Updates(event).Error; err != nil {
+					return err
+				}
+			}
+
+			if err := notifyRescheduled(tx, emailService, event, &previous); err != nil {
+				return err
+			}
+
+			if request.Capacity == nil {
+				continue
+			}
+
+			if err := promoteWaitlisted(tx, emailService, event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// applySeriesChanges copies the edited occurrence's details onto a series and
+// moves its schedule by shift.
+func applySeriesChanges(series *models.EventSeries, edited *models.Event, shift time.Duration) {
+	series.Title = edited.Title
+	series.Description = edited.Description
+	series.Location = edited.Location
+	series.Capacity = edited.Capacity
+	series.Visibility = edited.Visibility
+	series.RequiresApproval = edited.RequiresApproval
+	series.TimeZone = edited.TimeZone
+
+	series.StartTime = series.StartTime.Add(shift)
+	series.EndTime = series.StartTime.Add(edited.Duration())
+	for i := range series.ExDates {
+		series.ExDates[i] = series.ExDates[i].Add(shift)
+	}
+}
+
+// calendarDays counts the days between the dates of two local times.
+func calendarDays(from, to time.Time) int {
+	fromDate := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	toDate := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(toDate.Sub(fromDate).Hours() / 24)
+}
+
+// seriesHorizon is how far a rule is expanded: rules without COUNT or UNTIL
+// up to the series horizon, bounded rules to their end.
+func seriesHorizon(rule recurrence.Rule) time.Time {
+	if rule.Bounded() {
+		return time.Time{}
+	}
+	return time.Now().Add(models.SeriesHorizon)
+}
+
+// occurrenceStart is the start time the recurrence rule generated for an
+// occurrence, which stays fixed even if the occurrence was moved.
+func occurrenceStart(event *models.Event) time.Time {
+	if event.RecurrenceID != nil {
+		return *event.RecurrenceID
+	}
+	return event.DateTime
+}
+
+func orderByDateTime(db *gorm.DB) *gorm.DB {
+	return db.Order("date_time ASC")
+}
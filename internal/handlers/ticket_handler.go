@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pick-cee/events-api/internal/database"
+	"github.com/pick-cee/events-api/internal/middleware"
+	"github.com/pick-cee/events-api/internal/models"
+	"github.com/pick-cee/events-api/internal/utils"
+	qrcode "github.com/skip2/go-qrcode"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// default and bounds of the ?size= of a ticket QR code, in pixels
+	defaultQRCodeSize = 256
+	minQRCodeSize     = 128
+	maxQRCodeSize     = 1024
+)
+
+var (
+	errTicketNotFound     = errors.New("ticket not found")
+	errTicketNotConfirmed = errors.New("registration not confirmed")
+	errAlreadyCheckedIn   = errors.New("already checked in")
+)
+
+type CheckInRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TicketResponse struct {
+	RegistrationID uint         `json:"registration_id"`
+	EventID        uint         `json:"event_id"`
+	Code           string       `json:"code"`
+	QRCodeURL      string       `json:"qr_code_url"`
+	CheckedInAt    *time.Time   `json:"checked_in_at,omitempty"`
+	Event          models.Event `json:"event"`
+}
+
+// AttendanceResponse counts an event's registrations by status. Checked-in
+// attendees are counted among the confirmed ones.
+type AttendanceResponse struct {
+	EventID      uint  `json:"event_id"`
+	Capacity     int   `json:"capacity"`
+	Confirmed    int64 `json:"confirmed"`
+	Waitlisted   int64 `json:"waitlisted"`
+	Pending      int64 `json:"pending"`
+	CheckedIn    int64 `json:"checked_in"`
+	NotCheckedIn int64 `json:"not_checked_in"`
+}
+
+// GetTicket returns the ticket of one of the user's confirmed registrations.
+// Its code is what the QR code encodes and what organizers check in.
+func (h *RegistrationHandler) GetTicket(c *gin.Context) {
+	registration, ok := ownTicket(c)
+	if !ok {
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, TicketResponse{
+		RegistrationID: registration.ID,
+		EventID:        registration.EventID,
+		Code:           ticketCode(h.cfg.JWTSecret, registration),
+		QRCodeURL:      fmt.Sprintf("/api/v1/registrations/%d/ticket/qr", registration.ID),
+		CheckedInAt:    registration.CheckedInAt,
+		Event:          registration.Event,
+	})
+}
+
+// GetTicketQRCode renders the ticket code as a QR code, a PNG by default or
+// an SVG with ?format=svg. ?size= sets the width in pixels.
+func (h *RegistrationHandler) GetTicketQRCode(c *gin.Context) {
+	registration, ok := ownTicket(c)
+	if !ok {
+		return
+	}
+
+	size := defaultQRCodeSize
+	if value := c.Query("size"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < minQRCodeSize || parsed > maxQRCodeSize {
+			utils.ValidationErrorResponse(c, fmt.Sprintf("size must be between %d and %d", minQRCodeSize, maxQRCodeSize))
+			return
+		}
+		size = parsed
+	}
+
+	format := c.DefaultQuery("format", "png")
+	if format != "png" && format != "svg" {
+		utils.ValidationErrorResponse(c, "format must be png or svg")
+		return
+	}
+
+	code, err := qrcode.New(ticketCode(h.cfg.JWTSecret, registration), qrcode.Medium)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to render ticket")
+		return
+	}
+
+	// tickets stay valid until cancelled, but a cancelled one must not linger
+	// in shared caches
+	c.Header("Cache-Control", "private, no-store")
+
+	if format == "svg" {
+		c.Data(http.StatusOK, "image/svg+xml", qrCodeSVG(code.Bitmap(), size))
+		return
+	}
+
+	png, err := code.PNG(size)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to render ticket")
+		return
+	}
+	c.Data(http.StatusOK, "image/png", png)
+}
+
+// CheckInAttendee checks in the holder of a ticket. Each ticket checks in
+// once; scanning it again is rejected with the time of the first check-in.
+func (h *RegistrationHandler) CheckInAttendee(c *gin.Context) {
+	var request CheckInRequest
+
+	var event models.Event
+	if err := database.DB.First(&event, c.Param("id")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	if !canManageEvent(c, &event, models.CollaboratorPermissionCheckIn) {
+		utils.ErrorResponse(c, http.StatusForbidden, "You are not allowed to check attendees in to this event")
+		return
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.ValidationErrorResponse(c, err.Error())
+		return
+	}
+
+	registrationID, eventID, ok := parseTicketCode(h.cfg.JWTSecret, strings.TrimSpace(request.Code))
+	if !ok {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid ticket")
+		return
+	}
+
+	if eventID != event.ID {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Ticket is for another event")
+		return
+	}
+
+	operatorID := middleware.GetUserId(c)
+
+	var registration models.Registration
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// a share lock keeps the event from being cancelled mid check-in
+		// without serializing scans
+		if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).First(&event, event.ID).Error; err != nil {
+			return err
+		}
+
+		if event.IsCancelled() {
+			return errEventCancelled
+		}
+
+		// lock the registration so simultaneous scans check in only once
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("event_id = ?", event.ID).First(&registration, registrationID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errTicketNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if registration.Status != models.RegistrationStatusConfirmed {
+			return errTicketNotConfirmed
+		}
+
+		if registration.CheckedInAt != nil {
+			return errAlreadyCheckedIn
+		}
+
+		now := time.Now()
+		registration.CheckedInAt = &now
+		registration.CheckedInByID = &operatorID
+
+		return tx.Model(&registration).Updates(map[string]any{
+			"checked_in_at":    now,
+			"checked_in_by_id": operatorID,
+		}).Error
+	})
+
+	switch {
+	case errors.Is(err, errEventCancelled):
+		utils.ErrorResponse(c, http.StatusConflict, "Event is cancelled")
+		return
+	case errors.Is(err, errTicketNotFound):
+		utils.ErrorResponse(c, http.StatusNotFound, "Ticket is no longer valid")
+		return
+	case errors.Is(err, errTicketNotConfirmed):
+		utils.ErrorResponse(c, http.StatusConflict, "Registration is not confirmed")
+		return
+	case errors.Is(err, errAlreadyCheckedIn):
+		utils.ErrorResponse(c, http.StatusConflict, fmt.Sprintf("Ticket already checked in at %s", registration.CheckedInAt.Format(time.RFC3339)))
+		return
+	case err != nil:
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to check in attendee")
+		return
+	}
+
+	invalidateRegistrations(c.Request.Context(), []uint{registration.UserID}, event.ID)
+
+	database.DB.Preload("User").First(&registration, registration.ID)
+
+	utils.SuccessResponse(c, http.StatusOK, registration)
+}
+
+// GetAttendance returns live attendance counts for the door staff. It is
+// never cached.
+func (h *RegistrationHandler) GetAttendance(c *gin.Context) {
+	var event models.Event
+	if err := database.DB.First(&event, c.Param("id")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Event not found")
+		return
+	}
+
+	if !canManageEvent(c, &event, models.CollaboratorPermissionCheckIn) {
+		utils.ErrorResponse(c, http.StatusForbidden, "You are not allowed to check attendees in to this event")
+		return
+	}
+
+	var counts []struct {
+		Status    string
+		Total     int64
+		CheckedIn int64
+	}
+	if err := database.DB.Model(&models.Registration{}).
+		Select("status, COUNT(*) AS total, COUNT(checked_in_at) AS checked_in").
+		Where("event_id = ?", event.ID).Group("status").Scan(&counts).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to count attendance")
+		return
+	}
+
+	attendance := AttendanceResponse{EventID: event.ID, Capacity: event.Capacity}
+	for _, count := range counts {
+		switch count.Status {
+		case models.RegistrationStatusConfirmed:
+			attendance.Confirmed = count.Total
+			attendance.CheckedIn = count.CheckedIn
+		case models.RegistrationStatusWaitlisted:
+			attendance.Waitlisted = count.Total
+		case models.RegistrationStatusPending:
+			attendance.Pending = count.Total
+		}
+	}
+	attendance.NotCheckedIn = attendance.Confirmed - attendance.CheckedIn
+
+	utils.SuccessResponse(c, http.StatusOK, attendance)
+}
+
+// ownTicket loads the current user's registration named in the path. Only
+// confirmed registrations have a ticket.
+func ownTicket(c *gin.Context) (*models.Registration, bool) {
+	var registration models.Registration
+	if err := database.DB.Preload("Event").
+		Where("user_id = ?", middleware.GetUserId(c)).
+		First(&registration, c.Param("id")).Error; err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Registration not found")
+		return nil, false
+	}
+
+	if registration.Status != models.RegistrationStatusConfirmed {
+		utils.ErrorResponse(c, http.StatusConflict, "Tickets are issued once the registration is confirmed")
+		return nil, false
+	}
+
+	return &registration, true
+}
+
+// ticketCode signs the code of a registration's ticket. Cancelling the
+// registration invalidates it, since check-in looks the registration up.
+func ticketCode(secret string, registration *models.Registration) string {
+	return utils.SignToken(secret, fmt.Sprintf("ticket:%d:%d", registration.ID, registration.EventID))
+}
+
+// parseTicketCode verifies a code created by ticketCode.
+func parseTicketCode(secret, code string) (registrationID, eventID uint, ok bool) {
+	data, ok := utils.VerifySignedToken(secret, code)
+	if !ok {
+		return 0, 0, false
+	}
+
+	ids, found := strings.CutPrefix(data, "ticket:")
+	if !found {
+		return 0, 0, false
+	}
+
+	registration, event, found := strings.Cut(ids, ":")
+	if !found {
+		return 0, 0, false
+	}
+
+	parsedRegistration, err := strconv.ParseUint(registration, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	parsedEvent, err := strconv.ParseUint(event, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return uint(parsedRegistration), uint(parsedEvent), true
+}
+
+// qrCodeSVG draws a QR code bitmap, quiet zone included, as a square SVG of
+// the given width.
+func qrCodeSVG(bitmap [][]bool, size int) []byte {
+	modules := len(bitmap)
+
+	var svg strings.Builder
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size, modules, modules)
+	fmt.Fprintf(&svg, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, modules, modules)
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&svg, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+	svg.WriteString(`"/></svg>`)
+
+	return []byte(svg.String())
+}
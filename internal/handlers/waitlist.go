@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/pick-cee/events-api/internal/models"
+	"github.com/pick-cee/events-api/internal/services"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errEventNotFound        = errors.New("event not found")
+	errAlreadyRegistered    = errors.New("already registered")
+	errRegistrationNotFound = errors.New("registration not found")
+	errRegistrationRejected = errors.New("registration rejected")
+)
+
+// createRegistration registers a user for an event, taking a seat when one is
+// free and joining the waitlist otherwise. Events that require approval hold
+// the registration as pending instead. The event row is locked for the rest
+// of the transaction so concurrent registrations are counted one at a time.
+// answers must already be validated against the event's form.
+func createRegistration(tx *gorm.DB, event *models.Event, eventID any, userID uint, answers []models.RegistrationAnswer) (models.Registration, error) {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(event, eventID).Error; err != nil {
+		return models.Registration{}, errEventNotFound
+	}
+
+	if event.IsCancelled() {
+		return models.Registration{}, errEventCancelled
+	}
+
+	// check if already registered
+	var existingReg models.Registration
+	if err := tx.Where("user_id = ? AND event_id = ?", userID, event.ID).First(&existingReg).Error; err == nil {
+		if existingReg.Status == models.RegistrationStatusRejected {
+			return models.Registration{}, errRegistrationRejected
+		}
+		return models.Registration{}, errAlreadyRegistered
+	}
+
+	status := models.RegistrationStatusPending
+	if !event.RequiresApproval {
+		var err error
+		if status, err = nextRegistrationStatus(tx, event); err != nil {
+			return models.Registration{}, err
+		}
+	}
+
+	registration := models.Registration{
+		UserID:  userID,
+		EventID: event.ID,
+		Status:  status,
+		Answers: answers,
+	}
+
+	return registration, tx.Create(&registration).Error
+}
+
+// cancelRegistration deletes a user's registration and, when it held a seat,
+// hands the seat to the oldest waitlisted users. Rejected registrations are
+// kept so the user cannot apply again. The event row is locked for the rest
+// of the transaction.
+func cancelRegistration(tx *gorm.DB, emailService *services.EmailService, event *models.Event, eventID any, userID uint) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(event, eventID).Error; err != nil {
+		return errEventNotFound
+	}
+
+	var registration models.Registration
+	if err := tx.Where("user_id = ? AND event_id = ? AND status <> ?", userID, event.ID, models.RegistrationStatusRejected).First(&registration).Error; err != nil {
+		return errRegistrationNotFound
+	}
+
+	if err := tx.Delete(&registration).Error; err != nil {
+		return err
+	}
+
+	// a freed seat goes to the oldest waitlisted user
+	if registration.Status != models.RegistrationStatusConfirmed {
+		return nil
+	}
+
+	return promoteWaitlisted(tx, emailService, event)
+}
+
+// countConfirmed returns the number of seats taken for an event.
+func countConfirmed(tx *gorm.DB, eventID uint) (int64, error) {
+	var count int64
+	err := tx.Model(&models.Registration{}).
+		Where("event_id = ? AND status = ?", eventID, models.RegistrationStatusConfirmed).
+		Count(&count).Error
+	return count, err
+}
+
+// nextRegistrationStatus decides whether a new registration gets a seat or joins the waitlist.
+// The caller must hold a row lock on the event.
+func nextRegistrationStatus(tx *gorm.DB, event *models.Event) (string, error) {
+	if event.Capacity <= 0 {
+		return models.RegistrationStatusConfirmed, nil
+	}
+
+	confirmed, err := countConfirmed(tx, event.ID)
+	if err != nil {
+		return "", err
+	}
+
+	if confirmed >= int64(event.Capacity) {
+		return models.RegistrationStatusWaitlisted, nil
+	}
+	return models.RegistrationStatusConfirmed, nil
+}
+
+// promoteWaitlisted fills free seats with the oldest waitlisted registrations
+// and queues a promotion email for each promoted user who wants one.
+// The caller must hold a row lock on the event.
+func promoteWaitlisted(tx *gorm.DB, emailService *services.EmailService, event *models.Event) error {
+	query := tx.Preload("User").
+		Where("event_id = ? AND status = ?", event.ID, models.RegistrationStatusWaitlisted).
+		Order("created_at ASC, id ASC")
+
+	if event.Capacity > 0 {
+		confirmed, err := countConfirmed(tx, event.ID)
+		if err != nil {
+			return err
+		}
+
+		free := event.Capacity - int(confirmed)
+		if free <= 0 {
+			return nil
+		}
+		query = query.Limit(free)
+	}
+
+	var promoted []models.Registration
+	if err := query.Find(&promoted).Error; err != nil {
+		return err
+	}
+
+	if len(promoted) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(promoted))
+	for i := range promoted {
+		ids[i] = promoted[i].ID
+	}
+
+	if err := tx.Model(&models.Registration{}).Where("id IN ?", ids).
+		Update("status", models.RegistrationStatusConfirmed).Error; err != nil {
+		return err
+	}
+
+	userIDs := make([]uint, len(promoted))
+	for i, registration := range promoted {
+		userIDs[i] = registration.UserID
+	}
+
+	optedOut, err := services.OptedOutUsers(tx, services.NotificationWaitlistPromotion, userIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, registration := range promoted {
+		if optedOut[registration.UserID] {
+			continue
+		}
+		if err := emailService.SendWaitlistPromotionEmail(tx, registration.User.Email, registration.User.Name, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,312 @@
+// Package ical writes iCalendar (RFC 5545) calendars: events with their time
+// zones, alarms and revision status, as served in downloads and feeds.
+package ical
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const prodID = "-//pick-cee//events-api//EN"
+
+// Event statuses
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusTentative = "TENTATIVE"
+	StatusCancelled = "CANCELLED"
+)
+
+const (
+	// localLayout is a floating date-time, read in the zone of its TZID
+	localLayout = "20060102T150405"
+	utcLayout   = "20060102T150405Z"
+	// maxLineOctets is the longest content line before folding
+	maxLineOctets = 75
+)
+
+// Calendar is a VCALENDAR object. Method is left out when empty.
+type Calendar struct {
+	Name   string
+	Method string
+	// RefreshInterval tells subscribed clients how often to fetch the
+	// calendar again; zero leaves it to them
+	RefreshInterval time.Duration
+	Events          []Event
+}
+
+// Event is a VEVENT. Start and End are written in their location, with a
+// VTIMEZONE for every zone other than UTC. Sequence must grow with each
+// revision, so clients replace the copy they hold; a cancelled event keeps
+// its UID with Status set to StatusCancelled.
+type Event struct {
+	UID          string
+	Sequence     int
+	Status       string
+	Summary      string
+	Description  string
+	Location     string
+	URL          string
+	Start        time.Time
+	End          time.Time
+	Created      time.Time
+	LastModified time.Time
+	// Alarms are display reminders, each the time before Start it fires
+	Alarms []time.Duration
+}
+
+// Bytes encodes the calendar with CRLF line endings and folded lines.
+func (c *Calendar) Bytes() []byte {
+	w := &writer{}
+
+	w.line("BEGIN:VCALENDAR")
+	w.line("VERSION:2.0")
+	w.line("PRODID:" + prodID)
+	w.line("CALSCALE:GREGORIAN")
+	if c.Method != "" {
+		w.line("METHOD:" + c.Method)
+	}
+	if c.Name != "" {
+		w.line("X-WR-CALNAME:" + escapeText(c.Name))
+	}
+	if c.RefreshInterval > 0 {
+		interval := fmt.Sprintf("PT%dM", int(c.RefreshInterval/time.Minute))
+		w.line("REFRESH-INTERVAL;VALUE=DURATION:" + interval)
+		w.line("X-PUBLISHED-TTL:" + interval)
+	}
+
+	for _, zone := range c.zones() {
+		writeTimeZone(w, zone.location, zone.from, zone.to)
+	}
+
+	stamp := time.Now().UTC().Format(utcLayout)
+	for i := range c.Events {
+		writeEvent(w, &c.Events[i], stamp)
+	}
+
+	w.line("END:VCALENDAR")
+	return w.buf.Bytes()
+}
+
+type zoneRange struct {
+	location *time.Location
+	from, to time.Time
+}
+
+// zones returns each non-UTC zone the events use, with the span of their
+// times, in name order.
+func (c *Calendar) zones() []zoneRange {
+	byName := map[string]*zoneRange{}
+	for _, event := range c.Events {
+		location := event.Start.Location()
+		if isUTC(location) {
+			continue
+		}
+
+		zone, ok := byName[location.String()]
+		if !ok {
+			byName[location.String()] = &zoneRange{location: location, from: event.Start, to: event.End}
+			continue
+		}
+		if event.Start.Before(zone.from) {
+			zone.from = event.Start
+		}
+		if event.End.After(zone.to) {
+			zone.to = event.End
+		}
+	}
+
+	zones := make([]zoneRange, 0, len(byName))
+	for _, zone := range byName {
+		zones = append(zones, *zone)
+	}
+	sort.Slice(zones, func(i, j int) bool { return zones[i].location.String() < zones[j].location.String() })
+	return zones
+}
+
+func writeEvent(w *writer, event *Event, stamp string) {
+	w.line("BEGIN:VEVENT")
+	w.line("UID:" + escapeText(event.UID))
+	w.line("DTSTAMP:" + stamp)
+	w.line(dateTimeProperty("DTSTART", event.Start))
+	w.line(dateTimeProperty("DTEND", event.End))
+	w.line(fmt.Sprintf("SEQUENCE:%d", event.Sequence))
+	if event.Status != "" {
+		w.line("STATUS:" + event.Status)
+	}
+	w.line("SUMMARY:" + escapeText(event.Summary))
+	if event.Description != "" {
+		w.line("DESCRIPTION:" + escapeText(event.Description))
+	}
+	if event.Location != "" {
+		w.line("LOCATION:" + escapeText(event.Location))
+	}
+	if event.URL != "" {
+		w.line("URL:" + event.URL)
+	}
+	if !event.Created.IsZero() {
+		w.line("CREATED:" + event.Created.UTC().Format(utcLayout))
+	}
+	if !event.LastModified.IsZero() {
+		w.line("LAST-MODIFIED:" + event.LastModified.UTC().Format(utcLayout))
+	}
+
+	// a cancelled event should not keep reminding anyone
+	if event.Status != StatusCancelled {
+		for _, before := range event.Alarms {
+			w.line("BEGIN:VALARM")
+			w.line("ACTION:DISPLAY")
+			w.line("DESCRIPTION:" + escapeText(event.Summary))
+			w.line("TRIGGER:" + triggerDuration(before))
+			w.line("END:VALARM")
+		}
+	}
+
+	w.line("END:VEVENT")
+}
+
+// dateTimeProperty writes a UTC time in its Z form and any other time as a
+// local time with the TZID of its zone.
+func dateTimeProperty(name string, t time.Time) string {
+	if isUTC(t.Location()) {
+		return name + ":" + t.UTC().Format(utcLayout)
+	}
+	return fmt.Sprintf("%s;TZID=%s:%s", name, t.Location().String(), t.Format(localLayout))
+}
+
+// writeTimeZone writes a VTIMEZONE describing every offset change of the
+// zone during the years from and to fall in, plus the year around them.
+// Clients need it to read local times exactly as the server does.
+func writeTimeZone(w *writer, location *time.Location, from, to time.Time) {
+	start := time.Date(from.Year()-1, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(to.Year()+2, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	w.line("BEGIN:VTIMEZONE")
+	w.line("TZID:" + location.String())
+
+	// the offset in effect before the first change found
+	name, offset := start.In(location).Zone()
+	writeObservance(w, start.In(location).IsDST(), time.Date(1970, time.January, 1, 0, 0, 0, 0, time.UTC), name, offset, offset)
+
+	for _, transition := range transitions(location, start, end) {
+		_, fromOffset := transition.Add(-time.Second).In(location).Zone()
+		local := transition.In(location)
+		toName, toOffset := local.Zone()
+
+		// DTSTART is the onset in the local time that was in effect before it
+		onset := transition.Add(time.Duration(fromOffset) * time.Second).UTC()
+		writeObservance(w, local.IsDST(), onset, toName, fromOffset, toOffset)
+	}
+
+	w.line("END:VTIMEZONE")
+}
+
+func writeObservance(w *writer, daylight bool, onset time.Time, name string, fromOffset, toOffset int) {
+	component := "STANDARD"
+	if daylight {
+		component = "DAYLIGHT"
+	}
+
+	w.line("BEGIN:" + component)
+	w.line("DTSTART:" + onset.Format(localLayout))
+	w.line("TZOFFSETFROM:" + formatOffset(fromOffset))
+	w.line("TZOFFSETTO:" + formatOffset(toOffset))
+	w.line("TZNAME:" + escapeText(name))
+	w.line("END:" + component)
+}
+
+// transitions finds the instants the zone changes offset between start and
+// end, to the second. Zones change at most once a day, so probing daily and
+// bisecting each change is exact.
+func transitions(location *time.Location, start, end time.Time) []time.Time {
+	var found []time.Time
+
+	_, offset := start.In(location).Zone()
+	for day := start; day.Before(end); day = day.Add(24 * time.Hour) {
+		next := day.Add(24 * time.Hour)
+		_, nextOffset := next.In(location).Zone()
+		if nextOffset == offset {
+			continue
+		}
+
+		low, high := day, next
+		for high.Sub(low) > time.Second {
+			middle := low.Add(high.Sub(low) / 2).Truncate(time.Second)
+			if _, o := middle.In(location).Zone(); o == offset {
+				low = middle
+			} else {
+				high = middle
+			}
+		}
+		found = append(found, high)
+		offset = nextOffset
+	}
+	return found
+}
+
+// formatOffset renders a UTC offset in seconds as +HHMM, or +HHMMSS when it
+// has seconds.
+func formatOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+
+	offset := fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds%3600/60)
+	if seconds%60 != 0 {
+		offset += fmt.Sprintf("%02d", seconds%60)
+	}
+	return offset
+}
+
+// triggerDuration renders how long before the start an alarm fires, e.g.
+// -PT1440M.
+func triggerDuration(before time.Duration) string {
+	if before%time.Minute == 0 {
+		return fmt.Sprintf("-PT%dM", int(before/time.Minute))
+	}
+	return fmt.Sprintf("-PT%dS", int(before/time.Second))
+}
+
+func isUTC(location *time.Location) bool {
+	return location == time.UTC || location.String() == "UTC"
+}
+
+// escapeText escapes a TEXT value.
+func escapeText(value string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(value)
+}
+
+// writer emits content lines, folding them at 75 octets without splitting a
+// UTF-8 character.
+type writer struct {
+	buf bytes.Buffer
+}
+
+func (w *writer) line(content string) {
+	limit := maxLineOctets
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		w.buf.WriteString(content[:cut])
+		w.buf.WriteString("\r\n ")
+		content = content[cut:]
+		// continuation lines start with a space, which counts
+		limit = maxLineOctets - 1
+	}
+	w.buf.WriteString(content)
+	w.buf.WriteString("\r\n")
+}
@@ -0,0 +1,19 @@
+package jobs
+
+// Counts tallies what a job run did, e.g. the events and recipients a
+// reminder run covered. The scheduler stores it with the run's history.
+type Counts map[string]int
+
+func (c Counts) Add(key string, n int) {
+	c[key] += n
+}
+
+// Empty reports whether the run did nothing worth counting.
+func (c Counts) Empty() bool {
+	for _, n := range c {
+		if n != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -1,121 +1,252 @@
 package jobs
 
 import (
-	"context"
+	"database/sql"
 	"fmt"
 	"log"
 	"time"
 
-	"github.com/pick-cee/events-api/internal/cache"
 	"github.com/pick-cee/events-api/internal/database"
 	"github.com/pick-cee/events-api/internal/models"
 	"github.com/pick-cee/events-api/internal/services"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// reminderBatchSize bounds how many due reminders are loaded at once.
+const reminderBatchSize = 100
+
+// EventReminderJob sends the reminders of each event's schedule when they
+// fall due, computing each due time from the event's start and the offset.
+// Every reminder is recorded in the reminder_deliveries ledger.
 type EventReminderJob struct {
 	emailService *services.EmailService
+	maxAttempts  int
 }
 
-func NewEventReminderJob(emailService *services.EmailService) *EventReminderJob {
+func NewEventReminderJob(emailService *services.EmailService, maxAttempts int) *EventReminderJob {
 	return &EventReminderJob{
 		emailService: emailService,
+		maxAttempts:  maxAttempts,
 	}
 }
 
-// check 24hour remninders
-func (j *EventReminderJob) Check24HourReminders() error {
-	log.Println("⏰ Running 24-hour reminder job...")
+// dueReminder is a confirmed registration owed its reminder at OffsetMinutes,
+// either never queued (DeliveryID is nil) or failed and worth another attempt.
+type dueReminder struct {
+	RegistrationID uint
+	EventID        uint
+	UserID         uint
+	OffsetMinutes  int
+	DeliveryID     *uint
+}
 
-	ctx := context.Background()
-	now := time.Now()
-	tomorrow := now.Add(24 * time.Hour)
+// dueRemindersQuery picks, per confirmed registration of an upcoming event
+// that was not cancelled, the most recent reminder that is due, so late
+// registrants get the reminder they are owed. Older ones it supersedes are
+// never sent, so nobody gets a week's and a day's reminder at once. Reminders
+// already queued, sent or skipped are left out, as are failed ones that ran
+// out of attempts.
+const dueRemindersQuery = `
+SELECT due.registration_id, due.event_id, due.user_id, due.offset_minutes, d.id AS delivery_id FROM (
+	SELECT DISTINCT ON (r.id) r.id AS registration_id, r.event_id, r.user_id, er.offset_minutes
+	FROM registrations r
+	JOIN events e ON e.id = r.event_id AND e.deleted_at IS NULL AND e.status <> @cancelled
+	JOIN event_reminders er ON er.event_id = e.id
+	WHERE r.id > @after AND r.deleted_at IS NULL AND r.status = @status
+		AND e.date_time > @now
+		AND e.date_time - er.offset_minutes * interval '1 minute' <= @now
+	ORDER BY r.id, er.offset_minutes
+) due
+LEFT JOIN reminder_deliveries d ON d.registration_id = due.registration_id AND d.offset_minutes = due.offset_minutes
+WHERE d.id IS NULL OR (d.status = @failed AND d.attempts < @max_attempts)
+ORDER BY due.registration_id
+LIMIT @limit`
+
+// nextReminderQuery finds the earliest reminder falling due after now for an
+// event that has confirmed registrations.
+const nextReminderQuery = `
+SELECT MIN(e.date_time - er.offset_minutes * interval '1 minute')
+FROM event_reminders er
+JOIN events e ON e.id = er.event_id AND e.deleted_at IS NULL AND e.status <> @cancelled
+WHERE e.date_time - er.offset_minutes * interval '1 minute' > @now
+	AND EXISTS (
+		SELECT 1 FROM registrations r
+		WHERE r.event_id = e.id AND r.status = @status AND r.deleted_at IS NULL
+	)`
+
+// Run sends every reminder due now, then keeps sending the ones falling due
+// before until at their exact due time. The scheduler calls it once per poll
+// interval with until set to the next poll.
+func (j *EventReminderJob) Run(until time.Time) (Counts, error) {
+	counts := Counts{}
+	for {
+		if err := j.SendDueReminders(time.Now(), counts); err != nil {
+			return counts, err
+		}
 
-	// Find events happening in approximately 24 hours (±30 minutes)
-	var events []models.Event
-	err := database.DB.
-		Preload("Creator").
-		Preload("Registrations.User").
-		Where("date_time BETWEEN ? AND ?", tomorrow.Add(-30*time.Minute), tomorrow.Add(30*time.Minute)).
-		Find(&events).Error
+		next, err := j.NextDue(time.Now())
+		if err != nil {
+			return counts, err
+		}
+		if next.IsZero() || !next.Before(until) {
+			return counts, nil
+		}
 
-	if err != nil {
-		return fmt.Errorf("failed to fetch events: %w", err)
+		time.Sleep(time.Until(next))
 	}
+}
 
-	log.Printf("📧 Found %d events for 24-hour reminders\n", len(events))
-
-	// Send reminders
-	for _, event := range events {
-		// Check if we already sent 24h reminder
-		cacheKey := fmt.Sprintf("reminder:24h:%d", event.ID)
-		exists, _ := cache.Exists(ctx, cacheKey)
+// SendDueReminders queues every reminder due at now that is owed, including
+// failed ones with attempts left, and adds the events and recipients covered
+// to counts. A reminder that cannot be queued is logged and picked up again
+// on the next run.
+func (j *EventReminderJob) SendDueReminders(now time.Time, counts Counts) error {
+	var after uint
+	for {
+		var due []dueReminder
+		err := database.DB.Raw(dueRemindersQuery, map[string]any{
+			"after":        after,
+			"status":       models.RegistrationStatusConfirmed,
+			"cancelled":    models.EventStatusCancelled,
+			"now":          now,
+			"failed":       models.ReminderStatusFailed,
+			"max_attempts": j.maxAttempts,
+			"limit":        reminderBatchSize,
+		}).Scan(&due).Error
+		if err != nil {
+			return fmt.Errorf("failed to fetch due reminders: %w", err)
+		}
 
-		if exists == true {
-			log.Printf("⏭️  Already sent 24h reminder for event %d, skipping\n", event.ID)
-			continue
+		if len(due) > 0 {
+			log.Printf("📧 Found %d due event reminders\n", len(due))
 		}
 
-		// Send to all registered attendees
-		for _, registration := range event.Registrations {
-			err := j.emailService.Send24HourEventReminderEmail(registration.User.Email, registration.User.Name, &event)
+		events := map[uint]*models.Event{}
+		for _, reminder := range due {
+			after = reminder.RegistrationID
+
+			event, ok := events[reminder.EventID]
+			if !ok {
+				event = &models.Event{}
+				if err := database.DB.First(event, reminder.EventID).Error; err != nil {
+					return fmt.Errorf("failed to fetch event %d: %w", reminder.EventID, err)
+				}
+				events[reminder.EventID] = event
+				counts.Add("events", 1)
+			}
+
+			outcome, err := j.send(event, reminder)
 			if err != nil {
-				log.Printf("❌ Failed to send 24h reminder to %s: %v\n", registration.User.Email, err)
-			} else {
-				log.Printf("✅ Sent 24h reminder to %s for event: %s\n", registration.User.Email, event.Title)
+				counts.Add("errors", 1)
+				log.Printf("❌ Failed to queue reminder for registration %d: %v\n", reminder.RegistrationID, err)
+				continue
+			}
+			switch outcome {
+			case models.ReminderStatusQueued:
+				counts.Add("recipients", 1)
+			case models.ReminderStatusSkipped:
+				counts.Add("skipped", 1)
 			}
 		}
 
-		// Mark as sent (cache for 48 hours)
-		cache.Set(ctx, cacheKey, "sent", 48*time.Hour)
+		if len(due) < reminderBatchSize {
+			return nil
+		}
 	}
-	return nil
 }
 
-func (j *EventReminderJob) Check1HourReminders() error {
-	log.Println("⏰ Running 1-hour reminder job...")
+// send claims the reminder's ledger entry and queues the email in the same
+// transaction. A new entry is inserted and a failed one moved back to
+// queued; either write only succeeds for one worker, so a concurrent worker's
+// attempt is a no-op. Users who turned the reminder off get a skipped entry.
+// It returns the status the entry ended up with, or "" if it was not claimed.
+func (j *EventReminderJob) send(event *models.Event, reminder dueReminder) (string, error) {
+	var outcome string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		delivery, claimed, err := claimReminderDelivery(tx, reminder)
+		if err != nil || !claimed {
+			return err
+		}
 
-	ctx := context.Background()
-	now := time.Now()
-	oneHourLater := now.Add(1 * time.Hour)
-	log.Println(oneHourLater)
+		notificationType := services.ReminderNotificationType(reminder.OffsetMinutes)
+		wants, err := services.WantsEmail(tx, reminder.UserID, notificationType)
+		if err != nil {
+			return err
+		}
+		if !wants {
+			outcome = models.ReminderStatusSkipped
+			return tx.Model(delivery).Update("status", models.ReminderStatusSkipped).Error
+		}
 
-	// Find events happening in approximately 1 hour (±10 minutes)
-	var events []models.Event
-	err := database.DB.
-		Preload("Creator").
-		Preload("Registrations.User").
-		Where("date_time BETWEEN ? AND ?", oneHourLater.Add(-10*time.Minute), oneHourLater.Add(10*time.Minute)).
-		Find(&events).Error
+		var user models.User
+		if err := tx.First(&user, reminder.UserID).Error; err != nil {
+			return err
+		}
 
-	if err != nil {
-		return fmt.Errorf("failed to fetch events: %w", err)
-	}
+		message, err := j.emailService.SendEventReminderEmail(tx, user.Email, user.Name, event, reminder.OffsetMinutes)
+		if err != nil {
+			return err
+		}
 
-	log.Printf("📧 Found %d events for 1-hour reminders\n", len(events))
+		if err := tx.Model(delivery).Update("outbox_message_id", message.ID).Error; err != nil {
+			return err
+		}
 
-	for _, event := range events {
-		// Check if we already sent 1h reminder
-		cacheKey := fmt.Sprintf("reminder:1h:%d", event.ID)
-		exists, _ := cache.Exists(ctx, cacheKey)
+		outcome = models.ReminderStatusQueued
+		log.Printf("✅ Queued %d-minute reminder for %s for event: %s (attempt %d)\n", reminder.OffsetMinutes, user.Email, event.Title, delivery.Attempts)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return outcome, nil
+}
 
-		if exists == true {
-			log.Printf("⏭️  Already sent 1h reminder for event %d, skipping\n", event.ID)
-			continue
+// claimReminderDelivery records a new attempt at a reminder in the ledger.
+// It reports false when another worker got there first.
+func claimReminderDelivery(tx *gorm.DB, reminder dueReminder) (*models.ReminderDelivery, bool, error) {
+	if reminder.DeliveryID == nil {
+		delivery := &models.ReminderDelivery{
+			RegistrationID: reminder.RegistrationID,
+			OffsetMinutes:  reminder.OffsetMinutes,
+			EventID:        reminder.EventID,
+			Status:         models.ReminderStatusQueued,
+			Attempts:       1,
 		}
 
-		// Send to all registered attendees
-		for _, registration := range event.Registrations {
-			err := j.emailService.Send1HourEventReminderEmail(registration.User.Email, registration.User.Name, &event)
-			if err != nil {
-				log.Printf("❌ Failed to send 1h reminder to %s: %v\n", registration.User.Email, err)
-			} else {
-				log.Printf("✅ Sent 1h reminder to %s for event: %s\n", registration.User.Email, event.Title)
-			}
-		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(delivery)
+		return delivery, result.RowsAffected == 1, result.Error
+	}
+
+	result := tx.Model(&models.ReminderDelivery{}).
+		Where("id = ? AND status = ?", *reminder.DeliveryID, models.ReminderStatusFailed).
+		Updates(map[string]any{
+			"status":   models.ReminderStatusQueued,
+			"attempts": gorm.Expr("attempts + 1"),
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, false, result.Error
+	}
 
-		// Mark as sent (cache for 24 hours)
-		cache.Set(ctx, cacheKey, "sent", 24*time.Hour)
+	var delivery models.ReminderDelivery
+	if err := tx.First(&delivery, *reminder.DeliveryID).Error; err != nil {
+		return nil, false, err
 	}
+	return &delivery, true, nil
+}
 
-	return nil
+// NextDue returns when the next reminder falls due after now, or the zero
+// time when none is scheduled.
+func (j *EventReminderJob) NextDue(now time.Time) (time.Time, error) {
+	var next sql.NullTime
+	err := database.DB.Raw(nextReminderQuery, map[string]any{
+		"status":    models.RegistrationStatusConfirmed,
+		"cancelled": models.EventStatusCancelled,
+		"now":       now,
+	}).Row().Scan(&next)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to fetch next reminder: %w", err)
+	}
+	return next.Time, nil
 }
@@ -0,0 +1,31 @@
+package jobs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pick-cee/events-api/internal/database"
+	"github.com/pick-cee/events-api/internal/models"
+)
+
+// JobRunCleanupJob prunes job run history older than the retention period.
+type JobRunCleanupJob struct {
+	retention time.Duration
+}
+
+func NewJobRunCleanupJob(retention time.Duration) *JobRunCleanupJob {
+	return &JobRunCleanupJob{
+		retention: retention,
+	}
+}
+
+func (j *JobRunCleanupJob) Run() (Counts, error) {
+	result := database.DB.
+		Where("started_at < ? AND status <> ?", time.Now().Add(-j.retention), models.JobRunStatusRunning).
+		Delete(&models.JobRun{})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to delete job runs: %w", result.Error)
+	}
+
+	return Counts{"deleted": int(result.RowsAffected)}, nil
+}
@@ -0,0 +1,183 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/pick-cee/events-api/internal/database"
+	"github.com/pick-cee/events-api/internal/models"
+	"github.com/pick-cee/events-api/internal/services"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	outboxBatchSize = 50
+	// outboxSendTimeout bounds a single delivery
+	outboxSendTimeout = 10 * time.Second
+	// outboxClaimLease is how long claimed messages stay with their worker,
+	// enough for a whole batch to time out one by one. A worker that dies
+	// leaves its messages to be claimed again once the lease runs out.
+	outboxClaimLease = outboxBatchSize*outboxSendTimeout + time.Minute
+
+	// retries wait 30s, 1m, 2m, ... capped at 6h
+	outboxBaseBackoff = 30 * time.Second
+	outboxMaxBackoff  = 6 * time.Hour
+)
+
+type OutboxDeliveryJob struct {
+	emailService *services.EmailService
+	maxAttempts  int
+}
+
+func NewOutboxDeliveryJob(emailService *services.EmailService, maxAttempts int) *OutboxDeliveryJob {
+	return &OutboxDeliveryJob{
+		emailService: emailService,
+		maxAttempts:  maxAttempts,
+	}
+}
+
+// DeliverPending sends every due outbox message and counts the messages by
+// outcome. Messages are claimed with FOR UPDATE SKIP LOCKED, so several
+// workers never deliver the same message, and sent outside the claiming
+// transaction, so a failure to record one outcome never resends the others.
+func (j *OutboxDeliveryJob) DeliverPending() (Counts, error) {
+	counts := Counts{}
+	for {
+		delivered, err := j.deliverBatch(counts)
+		if err != nil {
+			return counts, err
+		}
+		if delivered < outboxBatchSize {
+			return counts, nil
+		}
+	}
+}
+
+func (j *OutboxDeliveryJob) deliverBatch(counts Counts) (int, error) {
+	messages, err := claimOutboxMessages()
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range messages {
+		message := &messages[i]
+		j.attempt(message)
+
+		if err := recordOutboxAttempt(message); err != nil {
+			// the message is claimed again once its lease runs out
+			counts.Add("errors", 1)
+			log.Printf("❌ Failed to record delivery of outbox message %d: %v\n", message.ID, err)
+			continue
+		}
+
+		counts.Add(message.Status, 1)
+	}
+
+	return len(messages), nil
+}
+
+// claimOutboxMessages leases a batch of due messages to this worker: pending
+// ones, and ones whose previous worker's lease ran out.
+func claimOutboxMessages() ([]models.OutboxMessage, error) {
+	var messages []models.OutboxMessage
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status IN ? AND next_attempt_at <= ?", []string{models.OutboxStatusPending, models.OutboxStatusSending}, now).
+			Order("next_attempt_at ASC, id ASC").
+			Limit(outboxBatchSize).
+			Find(&messages).Error; err != nil {
+			return fmt.Errorf("failed to fetch outbox messages: %w", err)
+		}
+
+		if len(messages) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, len(messages))
+		for i := range messages {
+			ids[i] = messages[i].ID
+			messages[i].Status = models.OutboxStatusSending
+		}
+
+		if err := tx.Model(&models.OutboxMessage{}).Where("id IN ?", ids).Updates(map[string]any{
+			"status":          models.OutboxStatusSending,
+			"next_attempt_at": now.Add(outboxClaimLease),
+		}).Error; err != nil {
+			return fmt.Errorf("failed to claim outbox messages: %w", err)
+		}
+		return nil
+	})
+
+	return messages, err
+}
+
+// recordOutboxAttempt writes the outcome of an attempt, with the reminder
+// ledger entry it settles, as long as the message is still claimed.
+func recordOutboxAttempt(message *models.OutboxMessage) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.OutboxMessage{ID: message.ID}).Where("status = ?", models.OutboxStatusSending).
+			Select("status", "attempts", "next_attempt_at", "last_error", "sent_at", "payload", "updated_at").
+			Updates(message)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// an admin replayed or withdrew it meanwhile
+			return nil
+		}
+
+		return services.SyncReminderDelivery(tx, message)
+	})
+}
+
+// attempt delivers a message and records the outcome on it: sent, scheduled
+// for a retry with exponential backoff, or dead once attempts run out.
+func (j *OutboxDeliveryJob) attempt(message *models.OutboxMessage) {
+	ctx, cancel := context.WithTimeout(context.Background(), outboxSendTimeout)
+	defer cancel()
+
+	message.Attempts++
+	err := j.emailService.Deliver(ctx, message)
+	now := time.Now()
+
+	if err == nil {
+		message.Status = models.OutboxStatusSent
+		message.SentAt = &now
+		message.LastError = ""
+		services.ScrubPayload(message.Payload)
+		return
+	}
+
+	message.LastError = err.Error()
+
+	// a scrubbed message cannot succeed on a later attempt either
+	if message.Attempts >= j.maxAttempts || errors.Is(err, services.ErrPayloadScrubbed) {
+		message.Status = models.OutboxStatusDead
+		services.ScrubPayload(message.Payload)
+		log.Printf("☠️  Outbox message %d (%s to %s) dead after %d attempts: %v\n", message.ID, message.Type, message.Recipient, message.Attempts, err)
+		return
+	}
+
+	message.Status = models.OutboxStatusPending
+	message.NextAttemptAt = now.Add(outboxBackoff(message.Attempts))
+	log.Printf("❌ Outbox message %d (%s to %s) failed, retrying at %s: %v\n", message.ID, message.Type, message.Recipient, message.NextAttemptAt.Format(time.RFC3339), err)
+}
+
+// outboxBackoff returns the delay before the next attempt after the given
+// number of failed attempts.
+func outboxBackoff(attempts int) time.Duration {
+	backoff := outboxBaseBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= outboxMaxBackoff {
+			return outboxMaxBackoff
+		}
+	}
+	return backoff
+}
@@ -0,0 +1,108 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/pick-cee/events-api/internal/cache"
+	"github.com/pick-cee/events-api/internal/database"
+	"github.com/pick-cee/events-api/internal/models"
+	"github.com/pick-cee/events-api/internal/recurrence"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SeriesExtensionJob materializes the occurrences of open-ended series, whose
+// rule has no COUNT or UNTIL, as they come within the series horizon.
+type SeriesExtensionJob struct{}
+
+func NewSeriesExtensionJob() *SeriesExtensionJob {
+	return &SeriesExtensionJob{}
+}
+
+// Run extends every open-ended series up to the horizon. A series that
+// cannot be extended is logged and tried again on the next run.
+func (j *SeriesExtensionJob) Run() (Counts, error) {
+	// rules are stored as rendered by Rule.String, so the parts are literal
+	var series []models.EventSeries
+	if err := database.DB.Where("rrule NOT LIKE ? AND rrule NOT LIKE ?", "%COUNT=%", "%UNTIL=%").
+		Find(&series).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch open-ended series: %w", err)
+	}
+
+	counts := Counts{"series": 0, "occurrences": 0}
+	horizon := time.Now().Add(models.SeriesHorizon)
+	for i := range series {
+		created, err := extendSeries(&series[i], horizon)
+		if err != nil {
+			log.Printf("❌ Failed to extend series %d: %v\n", series[i].ID, err)
+			counts.Add("errors", 1)
+			continue
+		}
+		if created > 0 {
+			counts.Add("series", 1)
+			counts.Add("occurrences", created)
+		}
+	}
+
+	// new occurrences can land on any list page
+	if counts["occurrences"] > 0 {
+		if err := cache.BumpNamespace(context.Background(), cache.EventListNamespace); err != nil {
+			log.Printf("❌ Failed to invalidate event list cache: %v\n", err)
+		}
+	}
+
+	return counts, nil
+}
+
+// extendSeries creates the occurrences of a series that start after its
+// latest one and up to horizon, and returns how many it created.
+func extendSeries(series *models.EventSeries, horizon time.Time) (int, error) {
+	var occurrences []models.Event
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// re-read under lock, the series may have been split or ended since
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(series, series.ID).Error; err != nil {
+			return err
+		}
+
+		rule, err := recurrence.Parse(series.RRule)
+		if err != nil {
+			return err
+		}
+		if rule.Bounded() {
+			return nil
+		}
+
+		// deleted occurrences count, so they are not created again
+		var latest *time.Time
+		if err := tx.Unscoped().Model(&models.Event{}).Where("series_id = ?", series.ID).
+			Select("MAX(recurrence_id)").Scan(&latest).Error; err != nil {
+			return err
+		}
+
+		// the rule has no COUNT, so expanding from the latest occurrence gives
+		// the same dates and only covers the new ones
+		dtstart := series.LocalStart()
+		if latest != nil {
+			dtstart = latest.In(dtstart.Location())
+		}
+
+		for _, start := range rule.Occurrences(dtstart, series.ExDates, recurrence.MaxCount, horizon) {
+			if latest == nil || start.After(*latest) {
+				occurrences = append(occurrences, series.NewOccurrence(start))
+			}
+		}
+
+		if len(occurrences) == 0 {
+			return nil
+		}
+		return tx.Create(&occurrences).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(occurrences), nil
+}
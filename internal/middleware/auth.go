@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
@@ -13,58 +14,109 @@ import (
 type Claims struct {
 	UserID uint   `json:"user_id"`
 	Email  string `json:"email"`
+	Role   string `json:"role"`
+	// FamilyID is the refresh token family the token was issued with
+	FamilyID string `json:"fid,omitempty"`
 	jwt.RegisteredClaims
 }
 
+func init() {
+	// issue times are compared with revocation cutoffs to the millisecond
+	jwt.TimePrecision = time.Millisecond
+}
+
 func AuthMidleware(cfg *config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get token from Authorization header
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
+		if c.GetHeader("Authorization") == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
 			c.Abort()
 			return
 		}
 
-		// Extract token
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
-			c.Abort()
+		if !authenticate(c, cfg) {
 			return
 		}
 
-		tokenString := parts[1]
+		c.Next()
 
-		// parse and validate token
-		token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(cfg.JWTSecret), nil
-		})
+	}
+}
 
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
+// OptionalAuth authenticates the request when it carries a token, for public
+// routes that show more to signed-in users. Anonymous requests pass through;
+// a token that is present but invalid is still rejected.
+func OptionalAuth(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
 			return
 		}
 
-		// extract claims
-		claims, ok := token.Claims.(*Claims)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
+		if !authenticate(c, cfg) {
 			return
 		}
 
-		// store user info in context
-		c.Set("user_id", claims.UserID)
-		c.Set("email", claims.Email)
-
 		c.Next()
+	}
+}
+
+// authenticate validates the bearer token and stores the user in the context,
+// aborting the request when the token is not acceptable.
+func authenticate(c *gin.Context, cfg *config.Config) bool {
+	// Extract token
+	parts := strings.Split(c.GetHeader("Authorization"), " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
+		c.Abort()
+		return false
+	}
+
+	tokenString := parts[1]
+
+	// parse and validate token
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(cfg.JWTSecret), nil
+	})
 
+	if err != nil || !token.Valid {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		c.Abort()
+		return false
 	}
+
+	// extract claims
+	claims, ok := token.Claims.(*Claims)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+		c.Abort()
+		return false
+	}
+
+	// reject tokens revoked by logout; fail closed if the list is unreachable
+	revoked, err := isRevoked(c.Request.Context(), claims)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Unable to verify token"})
+		c.Abort()
+		return false
+	}
+
+	if revoked {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+		c.Abort()
+		return false
+	}
+
+	// store user info in context
+	c.Set("user_id", claims.UserID)
+	c.Set("email", claims.Email)
+	c.Set("role", claims.Role)
+	c.Set("claims", claims)
+
+	return true
 }
 
 func GetUserId(c *gin.Context) uint {
@@ -83,3 +135,19 @@ func GetUserEmail(c *gin.Context) string {
 	}
 	return email.(string)
 }
+
+func GetUserRole(c *gin.Context) string {
+	role, exists := c.Get("role")
+	if !exists {
+		return ""
+	}
+	return role.(string)
+}
+
+func GetClaims(c *gin.Context) *Claims {
+	claims, exists := c.Get("claims")
+	if !exists {
+		return nil
+	}
+	return claims.(*Claims)
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pick-cee/events-api/internal/models"
+)
+
+// RequirePermission allows the request only if the role carried in the token
+// grants the permission. It must run after AuthMidleware.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !models.HasPermission(GetUserRole(c), permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to perform this action"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// HasPermission reports whether the authenticated user's role grants the
+// permission, for handlers that make finer-grained decisions.
+func HasPermission(c *gin.Context, permission string) bool {
+	return models.HasPermission(GetUserRole(c), permission)
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pick-cee/events-api/internal/database"
+	"github.com/redis/go-redis/v9"
+)
+
+// RevokeToken adds an access token's jti to the revocation list until the
+// token would have expired anyway.
+func RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if jti == "" || ttl <= 0 {
+		return nil
+	}
+	return database.RedisClient.Set(ctx, revokedTokenKey(jti), "revoked", ttl).Err()
+}
+
+// RevokeAllForUser rejects every access token issued to the user up to now.
+// The cutoff is kept in milliseconds, so a token issued right after it, as on
+// the next login, stays valid. The marker only needs to outlive the
+// longest-lived access token.
+func RevokeAllForUser(ctx context.Context, userID uint, accessTokenTTL time.Duration) error {
+	return database.RedisClient.Set(ctx, revokedUserKey(userID), time.Now().UnixMilli(), accessTokenTTL).Err()
+}
+
+// RevokeFamily rejects every access token issued along with a refresh token
+// of the family, leaving the user's other sessions alone.
+func RevokeFamily(ctx context.Context, familyID string, accessTokenTTL time.Duration) error {
+	if familyID == "" {
+		return nil
+	}
+	return database.RedisClient.Set(ctx, revokedFamilyKey(familyID), "revoked", accessTokenTTL).Err()
+}
+
+func isRevoked(ctx context.Context, claims *Claims) (bool, error) {
+	keys := []string{revokedTokenKey(claims.ID)}
+	if claims.FamilyID != "" {
+		keys = append(keys, revokedFamilyKey(claims.FamilyID))
+	}
+
+	exists, err := database.RedisClient.Exists(ctx, keys...).Result()
+	if err != nil {
+		return false, err
+	}
+	if exists > 0 {
+		return true, nil
+	}
+
+	cutoff, err := database.RedisClient.Get(ctx, revokedUserKey(claims.UserID)).Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	revokedAt, err := strconv.ParseInt(cutoff, 10, 64)
+	if err != nil {
+		return false, err
+	}
+	// markers written before cutoffs were kept in milliseconds hold seconds
+	if revokedAt < 1e12 {
+		revokedAt *= 1000
+	}
+
+	return claims.IssuedAt == nil || claims.IssuedAt.UnixMilli() <= revokedAt, nil
+}
+
+func revokedTokenKey(jti string) string {
+	return "revoked:jti:" + jti
+}
+
+func revokedUserKey(userID uint) string {
+	return fmt.Sprintf("revoked:user:%d", userID)
+}
+
+func revokedFamilyKey(familyID string) string {
+	return "revoked:family:" + familyID
+}
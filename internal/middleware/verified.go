@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pick-cee/events-api/internal/config"
+	"github.com/pick-cee/events-api/internal/database"
+	"github.com/pick-cee/events-api/internal/models"
+)
+
+// RequireVerifiedEmail blocks users who have not verified their email address
+// when REQUIRE_VERIFIED_EMAIL is enabled. It must run after AuthMidleware.
+func RequireVerifiedEmail(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.RequireVerifiedEmail {
+			c.Next()
+			return
+		}
+
+		var user models.User
+		if err := database.DB.Select("id", "email_verified_at").First(&user, GetUserId(c)).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+			c.Abort()
+			return
+		}
+
+		if !user.IsEmailVerified() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Please verify your email address first"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
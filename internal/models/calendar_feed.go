@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// CalendarFeed is a user's subscribable iCalendar feed. Its URL carries a
+// random token, since calendar apps cannot send an Authorization header; only
+// the SHA-256 hash of the token is stored.
+type CalendarFeed struct {
+	ID            uint       `gorm:"primaryKey" json:"-"`
+	UserID        uint       `gorm:"not null;uniqueIndex" json:"user_id"`
+	TokenHash     string     `gorm:"not null;uniqueIndex" json:"-"`
+	LastFetchedAt *time.Time `json:"last_fetched_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
@@ -1,21 +1,137 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// Event visibilities. Public events are listed and open to everyone, unlisted
+// ones are open to anyone with the link, and private ones only to their
+// organizers, registrants and invitees.
+const (
+	EventVisibilityPublic   = "public"
+	EventVisibilityUnlisted = "unlisted"
+	EventVisibilityPrivate  = "private"
+)
+
+// Event statuses. A cancelled event stays visible with its reason, but takes
+// no registrations and sends no reminders.
+const (
+	EventStatusScheduled = "scheduled"
+	EventStatusCancelled = "cancelled"
+)
+
 type Event struct {
-	ID            uint           `gorm:"primaryKey" json:"id"`
-	Title         string         `gorm:"not null" json:"title"`
-	Description   string         `json:"description"`
-	Location      string         `gorm:"not null" json:"location"`
-	DateTime      time.Time      `gorm:"not null" json:"date_time"`
-	CreatorID     uint           `gorm:"not null" json:"creator_id"`
-	Creator       User           `gorm:"foreignKey:CreatorID" json:"creator,omitempty"`
-	Registrations []Registration `gorm:"foreignKey:EventID" json:"registrations,omitempty"`
-	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at"`
-	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+	ID               uint                   `gorm:"primaryKey" json:"id"`
+	Title            string                 `gorm:"not null" json:"title"`
+	Description      string                 `json:"description"`
+	Location         string                 `gorm:"not null" json:"location"`
+	DateTime         time.Time              `gorm:"not null" json:"date_time"`
+	EndTime          time.Time              `json:"end_time"`
+	TimeZone         string                 `gorm:"type:varchar(64);not null;default:'UTC'" json:"time_zone"` // IANA name, e.g. Africa/Lagos
+	Capacity         int                    `gorm:"not null;default:0" json:"capacity"`                       // 0 means unlimited
+	Visibility       string                 `gorm:"type:varchar(20);not null;default:'public';index" json:"visibility"`
+	RequiresApproval bool                   `gorm:"not null;default:false" json:"requires_approval"` // new registrations wait for an organizer
+	Status           string                 `gorm:"type:varchar(20);not null;default:'scheduled';index" json:"status"`
+	CancelledAt      *time.Time             `json:"cancelled_at,omitempty"`
+	CancelReason     string                 `json:"cancel_reason,omitempty"`
+	CreatorID        uint                   `gorm:"not null" json:"creator_id"`
+	SeriesID         *uint                  `gorm:"index" json:"series_id,omitempty"`
+	RecurrenceID     *time.Time             `json:"recurrence_id,omitempty"` // original start of a series occurrence
+	Creator          User                   `gorm:"foreignKey:CreatorID" json:"creator,omitempty"`
+	Registrations    []Registration         `gorm:"foreignKey:EventID" json:"registrations,omitempty"`
+	Reminders        []EventReminder        `gorm:"foreignKey:EventID" json:"reminders,omitempty"`
+	Questions        []RegistrationQuestion `gorm:"foreignKey:EventID" json:"questions,omitempty"`
+	CreatedAt        time.Time              `json:"created_at"`
+	UpdatedAt        time.Time              `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt         `gorm:"index" json:"-"`
+}
+
+// IsPrivate reports whether the event is restricted to its organizers,
+// registrants and invitees.
+func (e *Event) IsPrivate() bool {
+	return e.Visibility == EventVisibilityPrivate
+}
+
+// IsCancelled reports whether the organizer called the event off.
+func (e *Event) IsCancelled() bool {
+	return e.Status == EventStatusCancelled
+}
+
+// TimeLocation returns the event's time zone, falling back to UTC when the
+// stored name is empty or unknown.
+func (e *Event) TimeLocation() *time.Location {
+	return LoadTimeLocation(e.TimeZone)
+}
+
+// LocalStart returns the start time in the event's time zone.
+func (e *Event) LocalStart() time.Time {
+	return e.DateTime.In(e.TimeLocation())
+}
+
+// LocalEnd returns the end time in the event's time zone.
+func (e *Event) LocalEnd() time.Time {
+	return e.EndTime.In(e.TimeLocation())
+}
+
+func (e *Event) Duration() time.Duration {
+	if e.EndTime.Before(e.DateTime) {
+		return 0
+	}
+	return e.EndTime.Sub(e.DateTime)
+}
+
+// Organizer is how an event's or series' creator appears in responses,
+// without the account details of the user.
+type Organizer struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// newOrganizer returns the organizer view of a creator, or nil when the
+// creator was not loaded.
+func newOrganizer(creator *User) *Organizer {
+	if creator.ID == 0 {
+		return nil
+	}
+	return &Organizer{ID: creator.ID, Name: creator.Name}
+}
+
+// MarshalJSON renders start and end in the event's time zone and adds the
+// matching UTC instants and the duration. The creator is shown as an
+// Organizer.
+func (e Event) MarshalJSON() ([]byte, error) {
+	type event Event
+	return json.Marshal(struct {
+		event
+		Creator         *Organizer `json:"creator,omitempty"`
+		DateTime        time.Time  `json:"date_time"`
+		EndTime         time.Time  `json:"end_time"`
+		DateTimeUTC     time.Time  `json:"date_time_utc"`
+		EndTimeUTC      time.Time  `json:"end_time_utc"`
+		DurationMinutes int        `json:"duration_minutes"`
+	}{
+		event:           event(e),
+		Creator:         newOrganizer(&e.Creator),
+		DateTime:        e.LocalStart(),
+		EndTime:         e.LocalEnd(),
+		DateTimeUTC:     e.DateTime.UTC(),
+		EndTimeUTC:      e.EndTime.UTC(),
+		DurationMinutes: int(e.Duration().Minutes()),
+	})
+}
+
+// LoadTimeLocation resolves an IANA time zone name, defaulting to UTC.
+func LoadTimeLocation(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
 }
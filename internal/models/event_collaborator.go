@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+)
+
+// Collaborator permissions, from broadest to narrowest. Each one includes
+// everything granted by the ones below it.
+const (
+	CollaboratorPermissionEdit      = "edit"      // edit details, cancel or delete the event
+	CollaboratorPermissionAttendees = "attendees" // view and manage attendees
+	CollaboratorPermissionCheckIn   = "checkin"   // check attendees in only
+)
+
+const (
+	CollaboratorStatusPending  = "pending"
+	CollaboratorStatusAccepted = "accepted"
+	CollaboratorStatusDeclined = "declined"
+)
+
+var collaboratorPermissionRank = map[string]int{
+	CollaboratorPermissionCheckIn:   1,
+	CollaboratorPermissionAttendees: 2,
+	CollaboratorPermissionEdit:      3,
+}
+
+// EventCollaborator is a co-organizer invited to help manage an event.
+// Invitations are addressed by email; UserID is set once the invitee has an
+// account.
+type EventCollaborator struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	EventID     uint       `gorm:"not null;uniqueIndex:idx_event_collaborator" json:"event_id"`
+	Email       string     `gorm:"not null;uniqueIndex:idx_event_collaborator" json:"email"`
+	UserID      *uint      `gorm:"index" json:"user_id,omitempty"`
+	Permission  string     `gorm:"type:varchar(20);not null" json:"permission"`
+	Status      string     `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	InvitedByID uint       `gorm:"not null" json:"invited_by_id"`
+	User        *User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Event       *Event     `gorm:"foreignKey:EventID" json:"event,omitempty"`
+	RespondedAt *time.Time `json:"responded_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func IsValidCollaboratorPermission(permission string) bool {
+	_, ok := collaboratorPermissionRank[permission]
+	return ok
+}
+
+// Allows reports whether an accepted collaborator's permission covers the
+// requested one.
+func (c *EventCollaborator) Allows(permission string) bool {
+	return c.Status == CollaboratorStatusAccepted &&
+		collaboratorPermissionRank[c.Permission] >= collaboratorPermissionRank[permission]
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Event invitation access levels.
+const (
+	InvitationAccessView     = "view"     // see the event and its attendees
+	InvitationAccessRegister = "register" // also register for it
+)
+
+// EventInvitation grants someone access to a private event. It is addressed by
+// email and carried by a signed link; UserID is set once the invitee accepts it
+// or registers with the link, after which the link is no longer needed.
+// Deleting the invitation revokes the link.
+type EventInvitation struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	EventID     uint       `gorm:"not null;uniqueIndex:idx_event_invitation" json:"event_id"`
+	Email       string     `gorm:"not null;uniqueIndex:idx_event_invitation" json:"email"`
+	UserID      *uint      `gorm:"index" json:"user_id,omitempty"`
+	Access      string     `gorm:"type:varchar(20);not null" json:"access"`
+	InvitedByID uint       `gorm:"not null" json:"invited_by_id"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	AcceptedAt  *time.Time `json:"accepted_at,omitempty"`
+	User        *User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Event       *Event     `gorm:"foreignKey:EventID" json:"event,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Expired reports whether the invitation no longer grants access.
+func (i *EventInvitation) Expired() bool {
+	return i.ExpiresAt != nil && !i.ExpiresAt.After(time.Now())
+}
+
+// AllowsRegistration reports whether the invitee may register, not just view.
+func (i *EventInvitation) AllowsRegistration() bool {
+	return i.Access == InvitationAccessRegister
+}
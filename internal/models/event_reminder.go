@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// DefaultReminderOffsets are the reminders an event gets when its organizer
+// does not choose any: 24 hours and one hour before it starts.
+var DefaultReminderOffsets = []int{24 * 60, 60}
+
+// EventReminder is one entry of an event's reminder schedule: registrants are
+// reminded OffsetMinutes before the event starts.
+type EventReminder struct {
+	ID            uint      `gorm:"primaryKey" json:"-"`
+	EventID       uint      `gorm:"not null;uniqueIndex:idx_event_reminder" json:"-"`
+	OffsetMinutes int       `gorm:"not null;uniqueIndex:idx_event_reminder" json:"offset_minutes"`
+	CreatedAt     time.Time `json:"-"`
+}
+
+// DueAt is when the reminder goes out for an event starting at start.
+func (r *EventReminder) DueAt(start time.Time) time.Time {
+	return start.Add(-time.Duration(r.OffsetMinutes) * time.Minute)
+}
+
+// NewEventReminders builds a reminder schedule from offsets in minutes.
+func NewEventReminders(offsets []int) []EventReminder {
+	reminders := make([]EventReminder, len(offsets))
+	for i, offset := range offsets {
+		reminders[i] = EventReminder{OffsetMinutes: offset}
+	}
+	return reminders
+}
+
+const (
+	ReminderStatusQueued  = "queued" // handed to the outbox
+	ReminderStatusSent    = "sent"
+	ReminderStatusFailed  = "failed"  // its outbox message went dead
+	ReminderStatusSkipped = "skipped" // the recipient turned the reminder off
+)
+
+// ReminderDelivery is the ledger entry for one registration's reminder at one
+// offset. Its unique key makes sure the reminder is queued once even when
+// several workers race for it; a failed delivery is queued again up to the
+// configured number of attempts.
+type ReminderDelivery struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	RegistrationID  uint       `gorm:"not null;uniqueIndex:idx_reminder_delivery" json:"registration_id"`
+	OffsetMinutes   int        `gorm:"not null;uniqueIndex:idx_reminder_delivery" json:"offset_minutes"`
+	EventID         uint       `gorm:"index" json:"event_id"`
+	Status          string     `gorm:"type:varchar(20);not null;default:'queued';index" json:"status"`
+	Attempts        int        `gorm:"not null;default:0" json:"attempts"`
+	OutboxMessageID *uint      `gorm:"index" json:"outbox_message_id,omitempty"`
+	LastError       string     `gorm:"type:text" json:"last_error,omitempty"`
+	SentAt          *time.Time `json:"sent_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
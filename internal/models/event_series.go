@@ -0,0 +1,94 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EventSeries describes a recurring event. Occurrences are materialized as
+// Event rows linked through SeriesID.
+type EventSeries struct {
+	ID               uint           `gorm:"primaryKey" json:"id"`
+	Title            string         `gorm:"not null" json:"title"`
+	Description      string         `json:"description"`
+	Location         string         `gorm:"not null" json:"location"`
+	Capacity         int            `gorm:"not null;default:0" json:"capacity"`
+	Visibility       string         `gorm:"type:varchar(20);not null;default:'public'" json:"visibility"` // copied to new occurrences
+	RequiresApproval bool           `gorm:"not null;default:false" json:"requires_approval"`              // copied to new occurrences
+	StartTime        time.Time      `gorm:"not null" json:"start_time"`
+	EndTime          time.Time      `json:"end_time"` // end of the first occurrence
+	TimeZone         string         `gorm:"type:varchar(64);not null;default:'UTC'" json:"time_zone"`
+	RRule            string         `gorm:"not null" json:"rrule"`
+	ExDates          []time.Time    `gorm:"serializer:json" json:"exdates"`
+	CreatorID        uint           `gorm:"not null" json:"creator_id"`
+	Creator          User           `gorm:"foreignKey:CreatorID" json:"creator,omitempty"`
+	Occurrences      []Event        `gorm:"foreignKey:SeriesID" json:"occurrences,omitempty"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// SeriesHorizon is how far ahead the occurrences of a series whose rule has
+// no COUNT or UNTIL are materialized. A scheduled job keeps them that far
+// ahead as time passes.
+const SeriesHorizon = 365 * 24 * time.Hour
+
+func (s *EventSeries) TableName() string {
+	return "event_series"
+}
+
+// LocalStart returns the first occurrence's start in the series' time zone,
+// which is the clock the recurrence rule is expanded in.
+func (s *EventSeries) LocalStart() time.Time {
+	return s.StartTime.In(LoadTimeLocation(s.TimeZone))
+}
+
+// Duration is the length of every occurrence generated by the series.
+func (s *EventSeries) Duration() time.Duration {
+	if s.EndTime.Before(s.StartTime) {
+		return 0
+	}
+	return s.EndTime.Sub(s.StartTime)
+}
+
+// NewOccurrence builds the occurrence of the series starting at start, with
+// the default reminder schedule.
+func (s *EventSeries) NewOccurrence(start time.Time) Event {
+	recurrenceID := start
+	return Event{
+		Title:            s.Title,
+		Description:      s.Description,
+		Location:         s.Location,
+		Capacity:         s.Capacity,
+		Visibility:       s.Visibility,
+		RequiresApproval: s.RequiresApproval,
+		Status:           EventStatusScheduled,
+		DateTime:         start,
+		EndTime:          start.Add(s.Duration()),
+		TimeZone:         s.TimeZone,
+		CreatorID:        s.CreatorID,
+		SeriesID:         &s.ID,
+		RecurrenceID:     &recurrenceID,
+		Reminders:        NewEventReminders(DefaultReminderOffsets),
+	}
+}
+
+// MarshalJSON renders the first occurrence in the series' time zone, and the
+// creator as an Organizer.
+func (s EventSeries) MarshalJSON() ([]byte, error) {
+	type series EventSeries
+	loc := LoadTimeLocation(s.TimeZone)
+	return json.Marshal(struct {
+		series
+		Creator   *Organizer `json:"creator,omitempty"`
+		StartTime time.Time  `json:"start_time"`
+		EndTime   time.Time  `json:"end_time"`
+	}{
+		series:    series(s),
+		Creator:   newOrganizer(&s.Creator),
+		StartTime: s.StartTime.In(loc),
+		EndTime:   s.EndTime.In(loc),
+	})
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+const (
+	JobRunStatusRunning   = "running"
+	JobRunStatusSucceeded = "succeeded"
+	JobRunStatusFailed    = "failed"
+)
+
+const (
+	JobTriggerSchedule = "schedule"
+	JobTriggerManual   = "manual"
+)
+
+// JobRun is the history entry of one run of a scheduled job. Counts holds
+// what the run did, e.g. the events and recipients a reminder run covered.
+type JobRun struct {
+	ID         uint           `gorm:"primaryKey" json:"id"`
+	Job        string         `gorm:"type:varchar(64);not null;index:idx_job_runs_job,priority:1" json:"job"`
+	Trigger    string         `gorm:"type:varchar(20);not null" json:"trigger"`
+	Status     string         `gorm:"type:varchar(20);not null;index" json:"status"`
+	Instance   string         `json:"instance"` // host name of the replica that ran it
+	StartedAt  time.Time      `gorm:"not null;index:idx_job_runs_job,priority:2" json:"started_at"`
+	FinishedAt *time.Time     `json:"finished_at,omitempty"`
+	Counts     map[string]int `gorm:"type:jsonb;serializer:json" json:"counts"`
+	Error      string         `gorm:"type:text" json:"error,omitempty"`
+}
+
+// JobState holds the controls of a scheduled job shared by every replica.
+// Jobs without a row run normally.
+type JobState struct {
+	Job       string    `gorm:"primaryKey;type:varchar(64)" json:"job"`
+	Paused    bool      `gorm:"not null;default:false" json:"paused"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// NotificationChannelEmail is the only delivery channel so far; preferences
+// are keyed by channel so others can be added.
+const NotificationChannelEmail = "email"
+
+// NotificationTypeAll is a preference covering every optional notification
+// type of a channel.
+const NotificationTypeAll = "all"
+
+// NotificationPreference records whether a user wants one notification type
+// on one channel. Types without a row are enabled.
+type NotificationPreference struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_notification_preference" json:"-"`
+	Type      string    `gorm:"type:varchar(64);not null;uniqueIndex:idx_notification_preference" json:"type"`
+	Channel   string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_notification_preference" json:"channel"`
+	Enabled   bool      `gorm:"not null" json:"enabled"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
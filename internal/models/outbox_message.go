@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+const (
+	OutboxStatusPending = "pending" // waiting for its first or next attempt
+	OutboxStatusSending = "sending" // claimed by a worker until next_attempt_at
+	OutboxStatusSent    = "sent"
+	OutboxStatusDead    = "dead"    // gave up after the last attempt
+	OutboxStatusSkipped = "skipped" // no longer worth sending, e.g. a reminder for a cancelled event
+)
+
+// OutboxMessage is a notification written in the same transaction as the
+// change that caused it and delivered later by the outbox worker.
+type OutboxMessage struct {
+	ID            uint           `gorm:"primaryKey" json:"id"`
+	Type          string         `gorm:"type:varchar(64);not null" json:"type"`
+	Recipient     string         `gorm:"not null" json:"recipient"`
+	Locale        string         `gorm:"type:varchar(10);not null;default:'en'" json:"locale"`
+	Payload       map[string]any `gorm:"type:jsonb;serializer:json" json:"payload"`
+	Status        string         `gorm:"type:varchar(20);not null;default:'pending';index:idx_outbox_due,priority:1" json:"status"`
+	Attempts      int            `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time      `gorm:"not null;index:idx_outbox_due,priority:2" json:"next_attempt_at"`
+	LastError     string         `gorm:"type:text" json:"last_error,omitempty"`
+	SentAt        *time.Time     `json:"sent_at,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
@@ -6,14 +6,35 @@ import (
 	"gorm.io/gorm"
 )
 
+// Registration statuses. Registrations for events that require approval
+// start pending; approving one gives it a seat or a waitlist place like any
+// other registration.
+const (
+	RegistrationStatusConfirmed  = "confirmed"
+	RegistrationStatusWaitlisted = "waitlisted"
+	RegistrationStatusPending    = "pending"
+	RegistrationStatusRejected   = "rejected"
+)
+
 type Registration struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	UserID    uint           `gorm:"not null" json:"user_id"`
-	EventID   uint           `gorm:"not null" json:"event_id"`
-	User      User           `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	Event     Event          `gorm:"foreignKey:EventID" json:"event,omitempty"`
-	CreatedAt time.Time      `json:"created_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID        uint                 `gorm:"primaryKey" json:"id"`
+	UserID    uint                 `gorm:"not null" json:"user_id"`
+	EventID   uint                 `gorm:"not null" json:"event_id"`
+	Status    string               `gorm:"type:varchar(20);not null;default:'confirmed';index" json:"status"`
+	User      User                 `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Event     Event                `gorm:"foreignKey:EventID" json:"event,omitempty"`
+	CreatedAt time.Time            `json:"created_at"`
+	DeletedAt gorm.DeletedAt       `gorm:"index" json:"-"`
+	Answers   []RegistrationAnswer `gorm:"type:jsonb;serializer:json" json:"answers,omitempty"`
+
+	// set when an organizer approves or rejects the registration
+	ReviewedByID  *uint      `json:"reviewed_by_id,omitempty"`
+	ReviewedAt    *time.Time `json:"reviewed_at,omitempty"`
+	ReviewMessage string     `gorm:"type:text" json:"review_message,omitempty"`
+
+	// set when an organizer scans the ticket at the door
+	CheckedInAt   *time.Time `gorm:"index" json:"checked_in_at,omitempty"`
+	CheckedInByID *uint      `json:"checked_in_by_id,omitempty"`
 }
 
 func (r *Registration) TableName() string {
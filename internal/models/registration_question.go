@@ -0,0 +1,35 @@
+package models
+
+// Registration question types.
+const (
+	QuestionTypeText         = "text"
+	QuestionTypeSingleChoice = "single_choice"
+	QuestionTypeMultiChoice  = "multi_choice"
+	QuestionTypeNumber       = "number"
+	QuestionTypeBoolean      = "boolean"
+)
+
+// RegistrationQuestion is one field of an event's registration form.
+type RegistrationQuestion struct {
+	ID       uint     `gorm:"primaryKey" json:"id"`
+	EventID  uint     `gorm:"not null;index" json:"-"`
+	Label    string   `gorm:"not null" json:"label"`
+	Type     string   `gorm:"type:varchar(20);not null" json:"type"`
+	Options  []string `gorm:"type:jsonb;serializer:json" json:"options,omitempty"` // choices of choice questions
+	Required bool     `gorm:"not null;default:false" json:"required"`
+	Position int      `gorm:"not null;default:0" json:"position"`
+}
+
+// IsChoice reports whether answers must be picked from Options.
+func (q *RegistrationQuestion) IsChoice() bool {
+	return q.Type == QuestionTypeSingleChoice || q.Type == QuestionTypeMultiChoice
+}
+
+// RegistrationAnswer is a registrant's answer to a question. The label is
+// copied so answers stay readable after the form changes. Value is a string,
+// a number, a boolean or, for multiple choice, a list of strings.
+type RegistrationAnswer struct {
+	QuestionID uint   `json:"question_id"`
+	Label      string `json:"label"`
+	Value      any    `json:"value"`
+}
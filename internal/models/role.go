@@ -0,0 +1,35 @@
+package models
+
+const (
+	RoleAdmin     = "admin"
+	RoleOrganizer = "organizer"
+	RoleAttendee  = "attendee"
+)
+
+// Permissions checked by middleware.RequirePermission.
+const (
+	PermissionCreateEvents    = "events:create"
+	PermissionManageAllEvents = "events:manage_all"
+	PermissionManageUsers     = "users:manage"
+)
+
+var rolePermissions = map[string][]string{
+	RoleAdmin:     {PermissionCreateEvents, PermissionManageAllEvents, PermissionManageUsers},
+	RoleOrganizer: {PermissionCreateEvents},
+	RoleAttendee:  {},
+}
+
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// HasPermission reports whether a role grants the permission.
+func HasPermission(role, permission string) bool {
+	for _, granted := range rolePermissions[role] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -8,14 +8,18 @@ import (
 )
 
 type User struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	Name      string         `gorm:"not null" json:"name"`
-	Email     string         `gorm:"not null;unique" json:"email"`
-	Password  string         `gorm:"not null" json:"-"`
-	Events    []Event        `gorm:"foreignKey:CreatorID" json:"events,omitempty"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID              uint           `gorm:"primaryKey" json:"id"`
+	Name            string         `gorm:"not null" json:"name"`
+	Email           string         `gorm:"not null;unique" json:"email"`
+	Password        string         `gorm:"not null" json:"-"`
+	Role            string         `gorm:"type:varchar(20);not null;default:'attendee'" json:"role"`
+	EmailVerifiedAt *time.Time     `json:"email_verified_at,omitempty"`
+	Locale          string         `gorm:"type:varchar(10);not null;default:'en'" json:"locale"` // language of notifications, e.g. fr
+	ListPublicly    bool           `gorm:"not null;default:false" json:"list_publicly"`          // opt-in to appear by name on public attendee lists
+	Events          []Event        `gorm:"foreignKey:CreatorID" json:"events,omitempty"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // hash password before creating
@@ -42,6 +46,21 @@ func (u *User) BeforeUpdate(tx *gorm.DB) error {
 	return nil
 }
 
+// SetPassword hashes a new password onto the user. Persist it with
+// UpdateColumn so the update hooks do not hash it a second time.
+func (u *User) SetPassword(password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	u.Password = string(hashedPassword)
+	return nil
+}
+
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
 // check if password is correct
 func (u *User) CheckPassword(password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
@@ -0,0 +1,403 @@
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+const untilLayout = "20060102T150405Z"
+
+// Bounds on how far a rule may run, so expanding one stays cheap.
+const (
+	MaxCount     = 1000
+	maxUntilSpan = 10 * 365 * 24 * time.Hour
+)
+
+// WeekdayNum is an RFC 5545 BYDAY entry. N is the optional ordinal used by
+// monthly rules ("2TU" = second Tuesday, "-1FR" = last Friday); 0 means every.
+type WeekdayNum struct {
+	Weekday time.Weekday
+	N       int
+}
+
+// Rule is the subset of an RFC 5545 RRULE supported for event series.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// Parse reads an RRULE value such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=10".
+// A leading "RRULE:" prefix is accepted.
+func Parse(value string) (Rule, error) {
+	rule := Rule{Interval: 1}
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return rule, errors.New("rrule is empty")
+	}
+
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok || val == "" {
+			return rule, fmt.Errorf("invalid rrule part %q", part)
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.Freq = Frequency(strings.ToUpper(val))
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return rule, fmt.Errorf("invalid INTERVAL %q", val)
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return rule, fmt.Errorf("invalid COUNT %q", val)
+			}
+			if n > MaxCount {
+				return rule, fmt.Errorf("COUNT may be at most %d", MaxCount)
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, err := parseUntil(val)
+			if err != nil {
+				return rule, err
+			}
+			if until.After(time.Now().Add(maxUntilSpan)) {
+				return rule, errors.New("UNTIL may be at most 10 years ahead")
+			}
+			rule.Until = until
+		case "BYDAY":
+			for _, code := range strings.Split(val, ",") {
+				day, err := parseWeekdayNum(code)
+				if err != nil {
+					return rule, err
+				}
+				rule.ByDay = append(rule.ByDay, day)
+			}
+		case "BYMONTHDAY":
+			for _, raw := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(raw)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return rule, fmt.Errorf("invalid BYMONTHDAY %q", raw)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		default:
+			return rule, fmt.Errorf("unsupported rrule part %q", key)
+		}
+	}
+
+	return rule, rule.validate()
+}
+
+func (r Rule) validate() error {
+	switch r.Freq {
+	case Daily, Weekly, Monthly:
+	case "":
+		return errors.New("FREQ is required")
+	default:
+		return fmt.Errorf("unsupported FREQ %q", r.Freq)
+	}
+
+	if r.Count > 0 && !r.Until.IsZero() {
+		return errors.New("COUNT and UNTIL cannot be combined")
+	}
+
+	if r.Freq == Daily && (len(r.ByDay) > 0 || len(r.ByMonthDay) > 0) {
+		return errors.New("BYDAY and BYMONTHDAY are not supported with FREQ=DAILY")
+	}
+
+	if r.Freq == Weekly {
+		if len(r.ByMonthDay) > 0 {
+			return errors.New("BYMONTHDAY is not supported with FREQ=WEEKLY")
+		}
+		for _, day := range r.ByDay {
+			if day.N != 0 {
+				return errors.New("ordinal BYDAY values require FREQ=MONTHLY")
+			}
+		}
+	}
+
+	if r.Freq == Monthly && len(r.ByDay) > 0 && len(r.ByMonthDay) > 0 {
+		return errors.New("BYDAY and BYMONTHDAY cannot be combined")
+	}
+
+	return nil
+}
+
+// String renders the rule back into RRULE syntax.
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+
+	if len(r.ByDay) > 0 {
+		codes := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			codes[i] = day.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, day := range r.ByMonthDay {
+			days[i] = strconv.Itoa(day)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilLayout))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+func (d WeekdayNum) String() string {
+	for code, weekday := range weekdayCodes {
+		if weekday == d.Weekday {
+			if d.N != 0 {
+				return strconv.Itoa(d.N) + code
+			}
+			return code
+		}
+	}
+	return ""
+}
+
+// ShiftDays moves the rule's BYDAY and BYMONTHDAY parts by a number of days,
+// for a series whose occurrences all moved by that much. Ordinals are kept,
+// so "2TU" shifted by a day becomes "2WE", and month days wrap around 31.
+func (r Rule) ShiftDays(days int) Rule {
+	if days == 0 {
+		return r
+	}
+
+	byDay := make([]WeekdayNum, len(r.ByDay))
+	for i, day := range r.ByDay {
+		byDay[i] = WeekdayNum{Weekday: time.Weekday(((int(day.Weekday)+days)%7 + 7) % 7), N: day.N}
+	}
+	r.ByDay = byDay
+
+	byMonthDay := make([]int, len(r.ByMonthDay))
+	for i, day := range r.ByMonthDay {
+		if day > 0 {
+			byMonthDay[i] = ((day-1+days)%31+31)%31 + 1
+		} else {
+			byMonthDay[i] = -(((-day-1-days)%31+31)%31 + 1)
+		}
+	}
+	r.ByMonthDay = byMonthDay
+	return r
+}
+
+// Bounded reports whether the rule ends by itself, through COUNT or UNTIL.
+func (r Rule) Bounded() bool {
+	return r.Count > 0 || !r.Until.IsZero()
+}
+
+// Occurrences expands the rule starting at dtstart. Generation stops at the
+// rule's COUNT or UNTIL, after limit results, or once horizon is passed. A
+// zero horizon means none; callers always pass a limit, which bounds the work
+// whatever the rule.
+// Start times listed in exdates are skipped but still count towards COUNT.
+func (r Rule) Occurrences(dtstart time.Time, exdates []time.Time, limit int, horizon time.Time) []time.Time {
+	excluded := make(map[int64]bool, len(exdates))
+	for _, exdate := range exdates {
+		excluded[exdate.Unix()] = true
+	}
+
+	var result []time.Time
+	generated := 0
+
+	for period := 0; ; period++ {
+		candidates := r.period(dtstart, period)
+		if len(candidates) == 0 && r.after(r.periodStart(dtstart, period), horizon) {
+			return result
+		}
+
+		for _, t := range candidates {
+			if t.Before(dtstart) {
+				continue
+			}
+			if r.after(t, horizon) {
+				return result
+			}
+
+			generated++
+			if !excluded[t.Unix()] {
+				result = append(result, t)
+			}
+
+			if (r.Count > 0 && generated >= r.Count) || (limit > 0 && len(result) >= limit) {
+				return result
+			}
+		}
+	}
+}
+
+// after reports whether t is past the rule's UNTIL or the horizon.
+func (r Rule) after(t, horizon time.Time) bool {
+	return (!horizon.IsZero() && t.After(horizon)) || (!r.Until.IsZero() && t.After(r.Until))
+}
+
+// periodStart returns the first instant of the n-th period of the rule.
+func (r Rule) periodStart(dtstart time.Time, n int) time.Time {
+	step := n * r.Interval
+	switch r.Freq {
+	case Weekly:
+		offset := (int(dtstart.Weekday()) + 6) % 7 // weeks start on Monday
+		return dtstart.AddDate(0, 0, 7*step-offset)
+	case Monthly:
+		return time.Date(dtstart.Year(), dtstart.Month()+time.Month(step), 1,
+			dtstart.Hour(), dtstart.Minute(), dtstart.Second(), 0, dtstart.Location())
+	default:
+		return dtstart.AddDate(0, 0, step)
+	}
+}
+
+// period returns the sorted candidate start times within the n-th period.
+func (r Rule) period(dtstart time.Time, n int) []time.Time {
+	start := r.periodStart(dtstart, n)
+
+	switch r.Freq {
+	case Weekly:
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []WeekdayNum{{Weekday: dtstart.Weekday()}}
+		}
+
+		var times []time.Time
+		for _, day := range days {
+			offset := (int(day.Weekday) + 6) % 7
+			times = append(times, start.AddDate(0, 0, offset))
+		}
+		return sortTimes(times)
+
+	case Monthly:
+		return sortTimes(r.monthDays(start, dtstart))
+
+	default:
+		return []time.Time{start}
+	}
+}
+
+func (r Rule) monthDays(monthStart, dtstart time.Time) []time.Time {
+	daysInMonth := monthStart.AddDate(0, 1, -1).Day()
+	at := func(day int) time.Time {
+		return monthStart.AddDate(0, 0, day-1)
+	}
+
+	var times []time.Time
+
+	if len(r.ByDay) > 0 {
+		for _, byDay := range r.ByDay {
+			var matches []time.Time
+			for day := 1; day <= daysInMonth; day++ {
+				if t := at(day); t.Weekday() == byDay.Weekday {
+					matches = append(matches, t)
+				}
+			}
+
+			switch {
+			case byDay.N == 0:
+				times = append(times, matches...)
+			case byDay.N > 0 && byDay.N <= len(matches):
+				times = append(times, matches[byDay.N-1])
+			case byDay.N < 0 && -byDay.N <= len(matches):
+				times = append(times, matches[len(matches)+byDay.N])
+			}
+		}
+		return times
+	}
+
+	monthDays := r.ByMonthDay
+	if len(monthDays) == 0 {
+		monthDays = []int{dtstart.Day()}
+	}
+
+	// days that do not exist in a month (e.g. the 31st in April) are skipped
+	for _, day := range monthDays {
+		if day < 0 {
+			day = daysInMonth + day + 1
+		}
+		if day >= 1 && day <= daysInMonth {
+			times = append(times, at(day))
+		}
+	}
+	return times
+}
+
+func sortTimes(times []time.Time) []time.Time {
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	return times
+}
+
+func parseUntil(value string) (time.Time, error) {
+	if t, err := time.Parse(untilLayout, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("20060102", value); err == nil {
+		// a date-only UNTIL includes the whole day
+		return t.Add(24*time.Hour - time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid UNTIL %q", value)
+}
+
+func parseWeekdayNum(code string) (WeekdayNum, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) < 2 {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", code)
+	}
+
+	weekday, ok := weekdayCodes[code[len(code)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", code)
+	}
+
+	day := WeekdayNum{Weekday: weekday}
+	if prefix := code[:len(code)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", code)
+		}
+		day.N = n
+	}
+
+	return day, nil
+}
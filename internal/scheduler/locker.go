@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+
+	"github.com/go-co-op/gocron/v2"
+)
+
+// errJobLocked is returned when another replica is running the job, which
+// makes gocron skip this run.
+var errJobLocked = errors.New("job is running on another instance")
+
+// advisoryLocker makes a job run on one replica at a time using PostgreSQL
+// session-level advisory locks. The lock lives as long as the connection that
+// took it, so unlike a lock with a TTL it cannot expire under a job that is
+// still running, and it is released if the replica dies mid-run.
+type advisoryLocker struct {
+	db *sql.DB
+}
+
+func newAdvisoryLocker(db *sql.DB) *advisoryLocker {
+	return &advisoryLocker{db: db}
+}
+
+// Lock takes the job's advisory lock on a dedicated connection, which is held
+// until Unlock.
+func (l *advisoryLocker) Lock(ctx context.Context, key string) (gocron.Lock, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a connection for lock %s: %w", key, err)
+	}
+
+	var acquired bool
+	err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock(hashtextextended($1, 0))", lockKey(key)).Scan(&acquired)
+	if err != nil || !acquired {
+		conn.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to take lock %s: %w", key, err)
+		}
+		return nil, errJobLocked
+	}
+
+	return &advisoryLock{conn: conn, key: key}, nil
+}
+
+type advisoryLock struct {
+	conn *sql.Conn
+	key  string
+}
+
+// Unlock releases the lock and returns the connection to the pool. If the
+// release fails the connection is discarded instead, since closing the
+// session is the only other way to drop the lock.
+func (l *advisoryLock) Unlock(ctx context.Context) error {
+	_, err := l.conn.ExecContext(ctx, "SELECT pg_advisory_unlock(hashtextextended($1, 0))", lockKey(l.key))
+	if err != nil {
+		// returning ErrBadConn makes database/sql close the connection
+		_ = l.conn.Raw(func(any) error { return driver.ErrBadConn })
+		return fmt.Errorf("failed to release lock %s: %w", l.key, err)
+	}
+
+	return l.conn.Close()
+}
+
+func lockKey(job string) string {
+	return "scheduler:" + job
+}
@@ -1,55 +1,368 @@
 package scheduler
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"os"
 	"time"
 
 	"github.com/go-co-op/gocron/v2"
+	"github.com/pick-cee/events-api/internal/config"
+	"github.com/pick-cee/events-api/internal/database"
 	"github.com/pick-cee/events-api/internal/jobs"
+	"github.com/pick-cee/events-api/internal/models"
 	"github.com/pick-cee/events-api/internal/services"
+	"gorm.io/gorm/clause"
 )
 
-func StartScheduler(emailService *services.EmailService) (gocron.Scheduler, error) {
-	// create a new scheduler
-	scheduler, err := gocron.NewScheduler()
+// Job names, which also key their distributed locks and run history.
+const (
+	JobEventReminders  = "event-reminders"
+	JobOutboxDelivery  = "outbox-delivery"
+	JobRunCleanup      = "job-run-cleanup"
+	JobSeriesExtension = "series-extension"
+)
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobRunning  = errors.New("job is already running")
+)
+
+// Scheduler runs the background jobs, records every run in the job_runs
+// table and lets admins trigger, pause and resume jobs.
+type Scheduler struct {
+	gocron.Scheduler
+	locker   *advisoryLocker
+	instance string
+	jobs     []*job
+}
+
+// job is a registered background job. Locked jobs run on one replica at a
+// time. Scheduled runs of quiet jobs are only recorded when they did
+// something or failed, since they run often and are mostly idle.
+type job struct {
+	name        string
+	description string
+	schedule    string
+	locked      bool
+	quiet       bool
+	run         func() (jobs.Counts, error)
+	cron        gocron.Job
+}
+
+// JobInfo describes a registered job for the admin API.
+type JobInfo struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Schedule    string         `json:"schedule"`
+	Paused      bool           `json:"paused"`
+	NextRun     *time.Time     `json:"next_run,omitempty"`
+	LastRun     *models.JobRun `json:"last_run,omitempty"`
+}
+
+func StartScheduler(cfg *config.Config, emailService *services.EmailService) (*Scheduler, error) {
+	sqlDB, err := database.DB.DB()
 	if err != nil {
 		return nil, err
 	}
+	locker := newAdvisoryLocker(sqlDB)
 
-	reminderJob := jobs.NewEventReminderJob(emailService)
-
-	// run 24-hour reminder every hour
-	_, err = scheduler.NewJob(
-		gocron.DurationJob(1*time.Hour),
-		gocron.NewTask(func() {
-			if err := reminderJob.Check24HourReminders(); err != nil {
-				log.Printf("❌ 24h reminder job failed: %v\n", err)
-			}
-		}),
+	// with several replicas, each job runs on whichever one takes its lock
+	// first; the others skip that run
+	cron, err := gocron.NewScheduler(
+		gocron.WithDistributedLocker(locker),
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	// run 1-hour reminder every 10 minutes
-	_, err = scheduler.NewJob(
-		gocron.DurationJob(10*time.Minute),
-		gocron.NewTask(func() {
-			if err := reminderJob.Check1HourReminders(); err != nil {
-				log.Printf("❌ 1h reminder job failed: %v\n", err)
-			}
-		}),
-	)
+	instance, _ := os.Hostname()
+	s := &Scheduler{
+		Scheduler: cron,
+		locker:    locker,
+		instance:  instance,
+	}
+
+	// runs this instance was in the middle of when it last stopped will
+	// never finish
+	if err := database.DB.Model(&models.JobRun{}).
+		Where("status = ? AND instance = ?", models.JobRunStatusRunning, instance).
+		Updates(map[string]any{"status": models.JobRunStatusFailed, "error": "interrupted by shutdown", "finished_at": time.Now()}).Error; err != nil {
+		return nil, err
+	}
+
+	reminderJob := jobs.NewEventReminderJob(emailService, cfg.ReminderMaxAttempts)
+	outboxJob := jobs.NewOutboxDeliveryJob(emailService, cfg.OutboxMaxAttempts)
+	cleanupJob := jobs.NewJobRunCleanupJob(cfg.JobRunRetention)
+	seriesJob := jobs.NewSeriesExtensionJob()
+
+	// send event reminders at their due times; each run covers one poll
+	// interval
+	err = s.add(&job{
+		name:        JobEventReminders,
+		description: "Sends each event's reminders at their due time",
+		schedule:    fmt.Sprintf("every %s, sending at due times", cfg.ReminderPollInterval),
+		locked:      true,
+		run: func() (jobs.Counts, error) {
+			return reminderJob.Run(time.Now().Add(cfg.ReminderPollInterval))
+		},
+	}, gocron.DurationJob(cfg.ReminderPollInterval))
+	if err != nil {
+		return nil, err
+	}
+
+	// deliver queued notifications. Messages are claimed with SKIP LOCKED, so
+	// replicas can deliver in parallel without a lock
+	err = s.add(&job{
+		name:        JobOutboxDelivery,
+		description: "Delivers queued notifications from the outbox",
+		schedule:    fmt.Sprintf("every %s", cfg.OutboxPollInterval),
+		quiet:       true,
+		run:         outboxJob.DeliverPending,
+	}, gocron.DurationJob(cfg.OutboxPollInterval))
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.add(&job{
+		name:        JobRunCleanup,
+		description: fmt.Sprintf("Deletes job run history older than %s", cfg.JobRunRetention),
+		schedule:    "every 24h",
+		locked:      true,
+		run:         cleanupJob.Run,
+	}, gocron.DurationJob(24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.add(&job{
+		name:        JobSeriesExtension,
+		description: "Creates the occurrences of open-ended series as they come within a year",
+		schedule:    "every 24h",
+		locked:      true,
+		run:         seriesJob.Run,
+	}, gocron.DurationJob(24*time.Hour))
 	if err != nil {
 		return nil, err
 	}
 
 	log.Println("✅ Scheduler started")
-	log.Println("  - 24h reminders: Every 1 hour")
-	log.Println("  - 1h reminders: Every 10 minutes")
+	log.Printf("  - Event reminders: At their due time, planned every %s\n", cfg.ReminderPollInterval)
+	log.Printf("  - Outbox delivery: Every %s\n", cfg.OutboxPollInterval)
+	log.Println("  - Job run cleanup: Every 24 hours")
+	log.Println("  - Series extension: Every 24 hours")
 
 	// Start scheduler
-	scheduler.Start()
+	s.Start()
+
+	return s, nil
+}
+
+// add registers a job with gocron. A slow run is never overlapped by the
+// next one.
+func (s *Scheduler) add(j *job, definition gocron.JobDefinition) error {
+	cronJob, err := s.NewJob(
+		definition,
+		gocron.NewTask(s.runScheduled, j),
+		gocron.WithName(j.name),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+		gocron.WithDisabledDistributedJobLocker(!j.locked),
+	)
+	if err != nil {
+		return err
+	}
+
+	j.cron = cronJob
+	s.jobs = append(s.jobs, j)
+	return nil
+}
+
+// runScheduled is the gocron task of every job: paused jobs are skipped,
+// other runs are recorded.
+func (s *Scheduler) runScheduled(j *job) {
+	paused, err := isPaused(j.name)
+	if err != nil {
+		log.Printf("❌ Failed to check whether %s is paused: %v\n", j.name, err)
+		return
+	}
+	if paused {
+		return
+	}
+
+	if j.quiet {
+		s.execute(j, s.newRun(j, models.JobTriggerSchedule))
+		return
+	}
+	s.execute(j, s.startRun(j, models.JobTriggerSchedule))
+}
+
+// newRun describes a run that begins now, without recording it yet.
+func (s *Scheduler) newRun(j *job, trigger string) *models.JobRun {
+	return &models.JobRun{
+		Job:       j.name,
+		Trigger:   trigger,
+		Status:    models.JobRunStatusRunning,
+		Instance:  s.instance,
+		StartedAt: time.Now(),
+	}
+}
+
+// startRun records that a run began. The job still runs if the record cannot
+// be written.
+func (s *Scheduler) startRun(j *job, trigger string) *models.JobRun {
+	run := s.newRun(j, trigger)
+	if err := database.DB.Create(run).Error; err != nil {
+		log.Printf("❌ Failed to record %s run: %v\n", j.name, err)
+	}
+	return run
+}
+
+// execute runs a job and records how it ended.
+func (s *Scheduler) execute(j *job, run *models.JobRun) {
+	counts, err := call(j)
+
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	run.Counts = counts
+	run.Status = models.JobRunStatusSucceeded
+	if err != nil {
+		run.Status = models.JobRunStatusFailed
+		run.Error = err.Error()
+		log.Printf("❌ %s job failed: %v\n", j.name, err)
+	}
+
+	// a quiet job's run is recorded only now, and only if it did something
+	if run.ID == 0 && (!j.quiet || (err == nil && counts.Empty())) {
+		return
+	}
+	if err := database.DB.Save(run).Error; err != nil {
+		log.Printf("❌ Failed to record %s run %d: %v\n", j.name, run.ID, err)
+	}
+}
+
+// call runs the job, turning a panic into an error so the run is recorded as
+// failed.
+func call(j *job) (counts jobs.Counts, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+	return j.run()
+}
+
+// Trigger starts a run of the job now, even if it is paused, and returns its
+// history entry. Locked jobs fail with ErrJobRunning while any replica runs
+// them.
+func (s *Scheduler) Trigger(name string) (models.JobRun, error) {
+	j := s.find(name)
+	if j == nil {
+		return models.JobRun{}, ErrJobNotFound
+	}
+
+	var lock gocron.Lock
+	if j.locked {
+		var err error
+		lock, err = s.locker.Lock(context.Background(), j.name)
+		if errors.Is(err, errJobLocked) {
+			return models.JobRun{}, ErrJobRunning
+		}
+		if err != nil {
+			return models.JobRun{}, err
+		}
+	}
+
+	run := s.startRun(j, models.JobTriggerManual)
+	started := *run
+
+	go func() {
+		if lock != nil {
+			defer func() {
+				if err := lock.Unlock(context.Background()); err != nil {
+					log.Printf("❌ Failed to unlock %s: %v\n", j.name, err)
+				}
+			}()
+		}
+		s.execute(j, run)
+	}()
+
+	return started, nil
+}
+
+// SetPaused pauses or resumes a job on every replica. A paused job skips its
+// scheduled runs until resumed.
+func (s *Scheduler) SetPaused(name string, paused bool) (JobInfo, error) {
+	j := s.find(name)
+	if j == nil {
+		return JobInfo{}, ErrJobNotFound
+	}
+
+	err := database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "job"}},
+		DoUpdates: clause.AssignmentColumns([]string{"paused", "updated_at"}),
+	}).Create(&models.JobState{Job: name, Paused: paused}).Error
+	if err != nil {
+		return JobInfo{}, err
+	}
+
+	return s.info(j)
+}
+
+// Jobs describes every registered job.
+func (s *Scheduler) Jobs() ([]JobInfo, error) {
+	infos := make([]JobInfo, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		info, err := s.info(j)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func (s *Scheduler) info(j *job) (JobInfo, error) {
+	paused, err := isPaused(j.name)
+	if err != nil {
+		return JobInfo{}, err
+	}
+
+	info := JobInfo{
+		Name:        j.name,
+		Description: j.description,
+		Schedule:    j.schedule,
+		Paused:      paused,
+	}
+
+	if next, err := j.cron.NextRun(); err == nil && !next.IsZero() && !paused {
+		info.NextRun = &next
+	}
+
+	var last models.JobRun
+	result := database.DB.Where("job = ?", j.name).Order("started_at DESC").Limit(1).Find(&last)
+	if result.Error != nil {
+		return JobInfo{}, result.Error
+	}
+	if result.RowsAffected > 0 {
+		info.LastRun = &last
+	}
+
+	return info, nil
+}
+
+func (s *Scheduler) find(name string) *job {
+	for _, j := range s.jobs {
+		if j.name == name {
+			return j
+		}
+	}
+	return nil
+}
 
-	return scheduler, nil
+func isPaused(name string) (bool, error) {
+	var count int64
+	err := database.DB.Model(&models.JobState{}).Where("job = ? AND paused", name).Count(&count).Error
+	return count > 0, err
 }
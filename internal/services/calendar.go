@@ -0,0 +1,149 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/pick-cee/events-api/internal/config"
+	"github.com/pick-cee/events-api/internal/ical"
+	"github.com/pick-cee/events-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// CalendarEventUID identifies an event in every calendar the API produces,
+// so a download, the feed and an email attachment update the same entry.
+func CalendarEventUID(cfg *config.Config, eventID uint) string {
+	host := "events-api"
+	if parsed, err := url.Parse(cfg.APIURL); err == nil && parsed.Hostname() != "" {
+		host = parsed.Hostname()
+	}
+	return fmt.Sprintf("event-%d@%s", eventID, host)
+}
+
+// CalendarEvent describes an event for a calendar, with an alarm per reminder
+// when its Reminders are loaded. changedAt is the latest change to the
+// entry outside the event itself, such as the registration being cancelled;
+// zero when there is none. Deleted and cancelled events are cancelled
+// whatever the status.
+//
+// SEQUENCE counts the seconds between the event's creation and its latest
+// change, so it grows with every revision without being stored.
+func CalendarEvent(cfg *config.Config, event *models.Event, status string, changedAt time.Time) ical.Event {
+	modified := event.UpdatedAt
+	if event.IsCancelled() {
+		status = ical.StatusCancelled
+	}
+	if event.DeletedAt.Valid {
+		status = ical.StatusCancelled
+		if event.DeletedAt.Time.After(modified) {
+			modified = event.DeletedAt.Time
+		}
+	}
+	if changedAt.After(modified) {
+		modified = changedAt
+	}
+
+	sequence := 0
+	if modified.After(event.CreatedAt) {
+		sequence = int(modified.Sub(event.CreatedAt) / time.Second)
+	}
+
+	alarms := make([]time.Duration, len(event.Reminders))
+	for i, reminder := range event.Reminders {
+		alarms[i] = time.Duration(reminder.OffsetMinutes) * time.Minute
+	}
+
+	return ical.Event{
+		UID:          CalendarEventUID(cfg, event.ID),
+		Sequence:     sequence,
+		Status:       status,
+		Summary:      event.Title,
+		Description:  event.Description,
+		Location:     event.Location,
+		URL:          fmt.Sprintf("%s/events/%d", cfg.AppURL, event.ID),
+		Start:        event.LocalStart(),
+		End:          event.LocalEnd(),
+		Created:      event.CreatedAt,
+		LastModified: modified,
+		Alarms:       alarms,
+	}
+}
+
+// Attachment is a file sent along with a notification.
+type Attachment struct {
+	Name    string
+	MIME    string
+	Content []byte
+}
+
+// withAttachments adds files to a payload under "attachments", in the
+// {file, name, mime} form Novu passes on to email providers, with the file
+// base64-encoded.
+func withAttachments(payload map[string]any, attachments ...Attachment) map[string]any {
+	files := make([]map[string]any, len(attachments))
+	for i, attachment := range attachments {
+		files[i] = map[string]any{
+			"name": attachment.Name,
+			"mime": attachment.MIME,
+			"file": base64.StdEncoding.EncodeToString(attachment.Content),
+		}
+	}
+	payload["attachments"] = files
+	return payload
+}
+
+// NotificationAttachments decodes the files added by withAttachments,
+// skipping malformed entries.
+func NotificationAttachments(payload map[string]any) []Attachment {
+	var entries []map[string]any
+	switch files := payload["attachments"].(type) {
+	case []map[string]any:
+		entries = files
+	case []any:
+		// as read back from the outbox
+		for _, file := range files {
+			if entry, ok := file.(map[string]any); ok {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	var attachments []Attachment
+	for _, entry := range entries {
+		name, _ := entry["name"].(string)
+		mime, _ := entry["mime"].(string)
+		encoded, _ := entry["file"].(string)
+
+		content, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || name == "" {
+			continue
+		}
+		attachments = append(attachments, Attachment{Name: name, MIME: mime, Content: content})
+	}
+	return attachments
+}
+
+// eventCalendarAttachment renders an event as an .ics file for an email,
+// confirmed unless the event was cancelled, loading its reminders as alarms
+// when needed.
+func (s *EmailService) eventCalendarAttachment(tx *gorm.DB, event *models.Event) (Attachment, error) {
+	entry := *event
+	if entry.Reminders == nil {
+		if err := tx.Where("event_id = ?", event.ID).Order("offset_minutes DESC").Find(&entry.Reminders).Error; err != nil {
+			return Attachment{}, err
+		}
+	}
+
+	calendar := ical.Calendar{
+		Method: "PUBLISH",
+		Events: []ical.Event{CalendarEvent(s.cfg, &entry, ical.StatusConfirmed, time.Time{})},
+	}
+
+	return Attachment{
+		Name:    "event.ics",
+		MIME:    "text/calendar; method=PUBLISH",
+		Content: calendar.Bytes(),
+	}, nil
+}
@@ -2,114 +2,344 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
 
-	novugo "github.com/novuhq/novu-go"
-	"github.com/novuhq/novu-go/models/components"
 	"github.com/pick-cee/events-api/internal/config"
 	"github.com/pick-cee/events-api/internal/models"
+	"gorm.io/gorm"
 )
 
+// eventTimeLayout renders times in the event's zone with its abbreviation,
+// e.g. "Monday, January 2, 2006 at 3:04 PM WAT".
+const eventTimeLayout = "Monday, January 2, 2006 at 3:04 PM MST"
+
+// Notification types, stored on outbox messages.
+const (
+	NotificationWelcome                = "welcome"
+	NotificationRegistrationConfirmed  = "registration_confirmed"
+	NotificationRegistrationCancelled  = "registration_cancelled"
+	NotificationEventReminder24h       = "event_reminder_24h"
+	NotificationEventReminder1h        = "event_reminder_1h"
+	NotificationEventReminder          = "event_reminder"
+	NotificationWaitlistPromotion      = "waitlist_promotion"
+	NotificationSeriesRegistration     = "series_registration_confirmed"
+	NotificationPasswordReset          = "password_reset"
+	NotificationEmailVerification      = "email_verification"
+	NotificationCollaboratorInvitation = "collaborator_invitation"
+	NotificationEventInvitation        = "event_invitation"
+	NotificationRegistrationApproved   = "registration_approved"
+	NotificationRegistrationRejected   = "registration_rejected"
+	NotificationEventCancelled         = "event_cancelled"
+	NotificationEventRescheduled       = "event_rescheduled"
+)
+
+// EmailService queues notifications in the outbox. The Send methods only
+// write an outbox message through the given transaction, so the message is
+// committed or rolled back together with the change it describes; Deliver
+// hands it to the configured Notifier later.
 type EmailService struct {
-	novuClient *novugo.Novu
+	notifier Notifier
+	cfg      *config.Config
 }
 
-func NewEmailService(cfg *config.Config) *EmailService {
-	secretKey := config.GetEnv("NOVU_SECRET_KEY", "")
-	client := novugo.New(novugo.WithSecurity(secretKey))
-
+func NewEmailService(cfg *config.Config, notifier Notifier) *EmailService {
 	return &EmailService{
-		novuClient: client,
+		notifier: notifier,
+		cfg:      cfg,
 	}
 }
 
-func (s *EmailService) SendWelcomeEmail(email, name string) error {
-	ctx := context.Background()
-	_, err := s.novuClient.Trigger(ctx, components.TriggerEventRequestDto{
-		WorkflowID: "golang-welcome-email",
-		Payload: map[string]any{
-			"name": name,
-		},
-		To: components.CreateToSubscriberPayloadDto(components.SubscriberPayloadDto{
-			Email:        &email,
-			SubscriberID: email,
-		}),
-	}, nil)
+// Deliver sends an outbox message through the notifier, with its sealed
+// values decrypted. The message ID is the idempotency key, so providers that
+// support it ignore a retry of a delivered message.
+func (s *EmailService) Deliver(ctx context.Context, message *models.OutboxMessage) error {
+	payload, err := s.openPayload(message.Payload)
+	if err != nil {
+		return err
+	}
+
+	return s.notifier.Notify(ctx, Notification{
+		ID:        "outbox-" + strconv.FormatUint(uint64(message.ID), 10),
+		Type:      message.Type,
+		Recipient: message.Recipient,
+		Locale:    message.Locale,
+		Payload:   payload,
+	})
+}
 
+// enqueue writes a notification to the outbox, due immediately. When the
+// address belongs to a user it is sent in their locale and carries their
+// unsubscribe and preferences links.
+func (s *EmailService) enqueue(tx *gorm.DB, notificationType, email string, payload map[string]any) error {
+	_, err := s.enqueueMessage(tx, notificationType, email, payload)
 	return err
 }
 
-func (s *EmailService) SendEventRegistrarionSuccessEmail(email, name string, event *models.Event) error {
-	ctx := context.Background()
-	_, err := s.novuClient.Trigger(ctx, components.TriggerEventRequestDto{
-		WorkflowID: "event-registration-success-email",
-		Payload: map[string]any{
-			"name":          name,
-			"eventTitle":    event.Title,
-			"eventTime":     event.DateTime.Format("Monday, January 2, 2006 at 3:04 PM"),
-			"eventLocation": event.Location,
-		},
-		To: components.CreateToSubscriberPayloadDto(components.SubscriberPayloadDto{
-			Email:        &email,
-			SubscriberID: email,
-		}),
-	}, nil)
+// enqueueMessage is enqueue for callers that track the outbox message.
+func (s *EmailService) enqueueMessage(tx *gorm.DB, notificationType, email string, payload map[string]any) (*models.OutboxMessage, error) {
+	locale := DefaultLocale
 
-	return err
+	var user models.User
+	if err := tx.Select("id", "locale").Where("email = ?", email).Take(&user).Error; err == nil {
+		if user.Locale != "" {
+			locale = user.Locale
+		}
+
+		// mandatory messages cannot be turned off, so their link turns off
+		// every optional one instead
+		unsubscribeType := notificationType
+		if IsMandatoryNotification(notificationType) {
+			unsubscribeType = models.NotificationTypeAll
+		}
+
+		token := UnsubscribeToken(s.cfg.JWTSecret, user.ID, unsubscribeType, models.NotificationChannelEmail)
+		payload["unsubscribeUrl"] = fmt.Sprintf("%s/api/v1/notifications/unsubscribe?token=%s", s.cfg.APIURL, url.QueryEscape(token))
+		payload["preferencesUrl"] = s.cfg.AppURL + "/settings/notifications"
+	}
+
+	message := &models.OutboxMessage{
+		Type:          notificationType,
+		Recipient:     email,
+		Locale:        locale,
+		Payload:       payload,
+		Status:        models.OutboxStatusPending,
+		NextAttemptAt: time.Now(),
+	}
+	if err := tx.Create(message).Error; err != nil {
+		return nil, err
+	}
+	return message, nil
 }
 
-func (s *EmailService) SendEventCancellationSuccessEmail(email, name string, event *models.Event) error {
-	ctx := context.Background()
-	_, err := s.novuClient.Trigger(ctx, components.TriggerEventRequestDto{
-		WorkflowID: "golang-event-registration-cancellation-email",
-		Payload: map[string]any{
-			"name":       name,
-			"eventTitle": event.Title,
-		},
-		To: components.CreateToSubscriberPayloadDto(components.SubscriberPayloadDto{
-			Email:        &email,
-			SubscriberID: email,
-		}),
-	}, nil)
+func (s *EmailService) SendWelcomeEmail(tx *gorm.DB, email, name string) error {
+	return s.enqueue(tx, NotificationWelcome, email, map[string]any{
+		"name": name,
+	})
+}
 
-	return err
+// SendEventRegistrarionSuccessEmail confirms a seat, with the event attached
+// as an .ics file.
+func (s *EmailService) SendEventRegistrarionSuccessEmail(tx *gorm.DB, email, name string, event *models.Event) error {
+	calendar, err := s.eventCalendarAttachment(tx, event)
+	if err != nil {
+		return err
+	}
+
+	return s.enqueue(tx, NotificationRegistrationConfirmed, email, withAttachments(withEventTimes(event, map[string]any{
+		"name":          name,
+		"eventTitle":    event.Title,
+		"eventLocation": event.Location,
+	}), calendar))
 }
 
-func (s *EmailService) Send24HourEventReminderEmail(email, name string, event *models.Event) error {
-	ctx := context.Background()
-	_, err := s.novuClient.Trigger(ctx, components.TriggerEventRequestDto{
-		WorkflowID: "golang-event-24h-reminder",
-		Payload: map[string]any{
-			"name":             name,
-			"eventTitle":       event.Title,
-			"eventLocation":    event.Location,
-			"eventTime":        event.DateTime.Format("Monday, January 2, 2006 at 3:04 PM"),
-			"eventDescription": event.Description,
-		},
-		To: components.CreateToSubscriberPayloadDto(components.SubscriberPayloadDto{
-			Email:        &email,
-			SubscriberID: email,
-		}),
-	}, nil)
+func (s *EmailService) SendEventCancellationSuccessEmail(tx *gorm.DB, email, name string, event *models.Event) error {
+	return s.enqueue(tx, NotificationRegistrationCancelled, email, withEventTimes(event, map[string]any{
+		"name":       name,
+		"eventTitle": event.Title,
+	}))
+}
 
-	return err
+// SendEventReminderEmail queues the reminder due offsetMinutes before the
+// event starts and returns its outbox message, which the reminder ledger
+// follows.
+func (s *EmailService) SendEventReminderEmail(tx *gorm.DB, email, name string, event *models.Event, offsetMinutes int) (*models.OutboxMessage, error) {
+	return s.enqueueMessage(tx, ReminderNotificationType(offsetMinutes), email, withEventTimes(event, map[string]any{
+		"name":                  name,
+		"eventTitle":            event.Title,
+		"eventLocation":         event.Location,
+		"eventDescription":      event.Description,
+		"reminderOffsetMinutes": offsetMinutes,
+	}))
 }
 
-func (s *EmailService) Send1HourEventReminderEmail(email, name string, event *models.Event) error {
-	ctx := context.Background()
-	_, err := s.novuClient.Trigger(ctx, components.TriggerEventRequestDto{
-		WorkflowID: "golang-event-1h-reminder",
-		Payload: map[string]any{
-			"name":             name,
-			"eventTitle":       event.Title,
-			"eventLocation":    event.Location,
-			"eventTime":        event.DateTime.Format("Monday, January 2, 2006 at 3:04 PM"),
-			"eventDescription": event.Description,
-		},
-		To: components.CreateToSubscriberPayloadDto(components.SubscriberPayloadDto{
-			Email:        &email,
-			SubscriberID: email,
-		}),
-	}, nil)
+// ReminderNotificationType is the notification type of the reminder sent
+// offsetMinutes before an event. The 24 hour and one hour reminders keep
+// their own types, so existing preferences and workflows still apply.
+func ReminderNotificationType(offsetMinutes int) string {
+	switch offsetMinutes {
+	case 24 * 60:
+		return NotificationEventReminder24h
+	case 60:
+		return NotificationEventReminder1h
+	default:
+		return NotificationEventReminder
+	}
+}
 
-	return err
+// IsReminderNotification reports whether a notification type is an event
+// reminder.
+func IsReminderNotification(notificationType string) bool {
+	switch notificationType {
+	case NotificationEventReminder, NotificationEventReminder24h, NotificationEventReminder1h:
+		return true
+	}
+	return false
+}
+
+func (s *EmailService) SendWaitlistPromotionEmail(tx *gorm.DB, email, name string, event *models.Event) error {
+	calendar, err := s.eventCalendarAttachment(tx, event)
+	if err != nil {
+		return err
+	}
+
+	return s.enqueue(tx, NotificationWaitlistPromotion, email, withAttachments(withEventTimes(event, map[string]any{
+		"name":          name,
+		"eventTitle":    event.Title,
+		"eventLocation": event.Location,
+	}), calendar))
+}
+
+// SendRegistrationApprovedEmail tells an applicant the organizer approved
+// them; waitlisted is set when the event was full by then. Applicants who got
+// a seat get the event attached as an .ics file.
+func (s *EmailService) SendRegistrationApprovedEmail(tx *gorm.DB, email, name string, event *models.Event, waitlisted bool, message string) error {
+	payload := withEventTimes(event, map[string]any{
+		"name":          name,
+		"eventTitle":    event.Title,
+		"eventLocation": event.Location,
+		"waitlisted":    waitlisted,
+		"message":       message,
+	})
+
+	if !waitlisted {
+		calendar, err := s.eventCalendarAttachment(tx, event)
+		if err != nil {
+			return err
+		}
+		payload = withAttachments(payload, calendar)
+	}
+
+	return s.enqueue(tx, NotificationRegistrationApproved, email, payload)
+}
+
+func (s *EmailService) SendRegistrationRejectedEmail(tx *gorm.DB, email, name string, event *models.Event, message string) error {
+	return s.enqueue(tx, NotificationRegistrationRejected, email, withEventTimes(event, map[string]any{
+		"name":          name,
+		"eventTitle":    event.Title,
+		"eventLocation": event.Location,
+		"message":       message,
+	}))
+}
+
+// SendEventCancelledEmail tells a registrant the organizer called the event
+// off. Those who held a seat get the cancelled event attached as an .ics
+// file, so calendars that imported it drop it.
+func (s *EmailService) SendEventCancelledEmail(tx *gorm.DB, email, name string, event *models.Event, attachCalendar bool) error {
+	payload := withEventTimes(event, map[string]any{
+		"name":          name,
+		"eventTitle":    event.Title,
+		"eventLocation": event.Location,
+		"reason":        event.CancelReason,
+	})
+
+	if attachCalendar {
+		calendar, err := s.eventCalendarAttachment(tx, event)
+		if err != nil {
+			return err
+		}
+		payload = withAttachments(payload, calendar)
+	}
+
+	return s.enqueue(tx, NotificationEventCancelled, email, payload)
+}
+
+// SendEventRescheduledEmail tells a registrant the event moved, with the
+// schedule and location it had before. Those who hold a seat get the updated
+// event attached as an .ics file.
+func (s *EmailService) SendEventRescheduledEmail(tx *gorm.DB, email, name string, event, previous *models.Event, attachCalendar bool) error {
+	payload := withEventTimes(event, map[string]any{
+		"name":                  name,
+		"eventTitle":            event.Title,
+		"eventLocation":         event.Location,
+		"timeChanged":           !event.DateTime.Equal(previous.DateTime) || !event.EndTime.Equal(previous.EndTime),
+		"locationChanged":       event.Location != previous.Location,
+		"previousEventTime":     previous.LocalStart().Format(eventTimeLayout),
+		"previousEventTimeZone": previous.TimeZone,
+		"previousEventTimeUTC":  previous.DateTime.UTC().Format(time.RFC3339),
+		"previousEventLocation": previous.Location,
+	})
+
+	if attachCalendar {
+		calendar, err := s.eventCalendarAttachment(tx, event)
+		if err != nil {
+			return err
+		}
+		payload = withAttachments(payload, calendar)
+	}
+
+	return s.enqueue(tx, NotificationEventRescheduled, email, payload)
+}
+
+func (s *EmailService) SendSeriesRegistrationSuccessEmail(tx *gorm.DB, email, name string, series *models.EventSeries, occurrences int) error {
+	return s.enqueue(tx, NotificationSeriesRegistration, email, map[string]any{
+		"name":               name,
+		"seriesTitle":        series.Title,
+		"seriesLocation":     series.Location,
+		"seriesStartTime":    series.LocalStart().Format(eventTimeLayout),
+		"seriesStartTimeUTC": series.StartTime.UTC().Format(time.RFC3339),
+		"seriesTimeZone":     series.TimeZone,
+		"occurrences":        occurrences,
+	})
+}
+
+// withEventTimes adds the event's schedule to a workflow payload, rendered in
+// the event's own time zone with the UTC instants alongside.
+func withEventTimes(event *models.Event, payload map[string]any) map[string]any {
+	payload["eventTime"] = event.LocalStart().Format(eventTimeLayout)
+	payload["eventEndTime"] = event.LocalEnd().Format(eventTimeLayout)
+	payload["eventTimeZone"] = event.TimeZone
+	payload["eventTimeUTC"] = event.DateTime.UTC().Format(time.RFC3339)
+	payload["eventEndTimeUTC"] = event.EndTime.UTC().Format(time.RFC3339)
+	return payload
+}
+
+// SendPasswordResetEmail queues a reset link. The link carries the raw token,
+// so it is sealed in the outbox.
+func (s *EmailService) SendPasswordResetEmail(tx *gorm.DB, email, name, resetURL string, expiresAt time.Time) error {
+	payload, err := s.withSealed(map[string]any{
+		"name":      name,
+		"expiresAt": expiresAt.UTC().Format(time.RFC3339),
+	}, "resetUrl", resetURL)
+	if err != nil {
+		return err
+	}
+
+	return s.enqueue(tx, NotificationPasswordReset, email, payload)
+}
+
+// SendEmailVerificationEmail queues a verification link, sealed in the
+// outbox like a reset link.
+func (s *EmailService) SendEmailVerificationEmail(tx *gorm.DB, email, name, verifyURL string, expiresAt time.Time) error {
+	payload, err := s.withSealed(map[string]any{
+		"name":      name,
+		"expiresAt": expiresAt.UTC().Format(time.RFC3339),
+	}, "verifyUrl", verifyURL)
+	if err != nil {
+		return err
+	}
+
+	return s.enqueue(tx, NotificationEmailVerification, email, payload)
+}
+
+func (s *EmailService) SendCollaboratorInvitationEmail(tx *gorm.DB, email, inviterName string, event *models.Event, permission, acceptURL string) error {
+	return s.enqueue(tx, NotificationCollaboratorInvitation, email, withEventTimes(event, map[string]any{
+		"inviterName":   inviterName,
+		"eventTitle":    event.Title,
+		"eventLocation": event.Location,
+		"permission":    permission,
+		"acceptUrl":     acceptURL,
+	}))
+}
+
+func (s *EmailService) SendEventInvitationEmail(tx *gorm.DB, email, inviterName string, event *models.Event, canRegister bool, inviteURL string) error {
+	return s.enqueue(tx, NotificationEventInvitation, email, withEventTimes(event, map[string]any{
+		"inviterName":   inviterName,
+		"eventTitle":    event.Title,
+		"eventLocation": event.Location,
+		"canRegister":   canRegister,
+		"inviteUrl":     inviteURL,
+	}))
 }
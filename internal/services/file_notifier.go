@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileNotifier renders notifications from the local templates and appends
+// them as JSON lines to a file or stdout, for development and CI.
+type FileNotifier struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewFileNotifier writes to path, or to stdout when path is empty or "-".
+func NewFileNotifier(path string) (*FileNotifier, error) {
+	if path == "" || path == "-" {
+		return &FileNotifier{w: os.Stdout}, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open notification file: %w", err)
+	}
+	return &FileNotifier{w: file}, nil
+}
+
+func (n *FileNotifier) Notify(ctx context.Context, notification Notification) error {
+	message, err := RenderNotification(notification)
+	if err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(struct {
+		ID          string    `json:"id"`
+		Type        string    `json:"type"`
+		To          string    `json:"to"`
+		Locale      string    `json:"locale"`
+		Version     string    `json:"version"`
+		Subject     string    `json:"subject"`
+		Text        string    `json:"text"`
+		HTML        string    `json:"html"`
+		Attachments []string  `json:"attachments,omitempty"`
+		Timestamp   time.Time `json:"timestamp"`
+	}{
+		ID:          notification.ID,
+		Type:        notification.Type,
+		To:          notification.Recipient,
+		Locale:      message.Locale,
+		Version:     message.Version,
+		Subject:     message.Subject,
+		Text:        message.Text,
+		HTML:        message.HTML,
+		Attachments: attachmentNames(notification.Payload),
+		Timestamp:   time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	_, err = n.w.Write(append(line, '\n'))
+	return err
+}
+
+// attachmentNames lists the files attached to a notification; their content
+// is left out of the log.
+func attachmentNames(payload map[string]any) []string {
+	var names []string
+	for _, attachment := range NotificationAttachments(payload) {
+		names = append(names, attachment.Name)
+	}
+	return names
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pick-cee/events-api/internal/models"
+)
+
+// timeFormatters render an RFC 3339 instant in an IANA zone for a locale.
+// Locales without an entry use eventTimeLayout.
+var timeFormatters = map[string]func(t time.Time) string{
+	"fr": func(t time.Time) string {
+		weekdays := []string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"}
+		months := []string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"}
+		zone, _ := t.Zone()
+		return fmt.Sprintf("%s %d %s %d à %s (%s)", weekdays[t.Weekday()], t.Day(), months[t.Month()-1], t.Year(), t.Format("15:04"), zone)
+	},
+}
+
+// offsetUnits name the units of a reminder offset per locale, largest first,
+// as singular and plural forms. Locales without an entry use English.
+var offsetUnits = map[string][]offsetUnit{
+	"en": {
+		{7 * 24 * 60, "week", "weeks"},
+		{24 * 60, "day", "days"},
+		{60, "hour", "hours"},
+		{1, "minute", "minutes"},
+	},
+	"fr": {
+		{7 * 24 * 60, "semaine", "semaines"},
+		{24 * 60, "jour", "jours"},
+		{60, "heure", "heures"},
+		{1, "minute", "minutes"},
+	},
+}
+
+type offsetUnit struct {
+	minutes          int
+	singular, plural string
+}
+
+// formatOffset renders a number of minutes in the largest unit that divides
+// it evenly, e.g. "1 week", "36 hours" or "15 minutes".
+func formatOffset(units []offsetUnit, minutes int) string {
+	for _, unit := range units {
+		if minutes%unit.minutes != 0 {
+			continue
+		}
+
+		count := minutes / unit.minutes
+		if count == 1 {
+			return "1 " + unit.singular
+		}
+		return strconv.Itoa(count) + " " + unit.plural
+	}
+	return strconv.Itoa(minutes)
+}
+
+// templateFuncs returns the template functions for a locale. formatTime takes
+// an RFC 3339 timestamp and an IANA zone name, so translated templates can
+// render the UTC instants in the payload instead of the English strings;
+// formatOffset renders a reminder offset given in minutes.
+func templateFuncs(locale string) map[string]any {
+	format, ok := timeFormatters[locale]
+	if !ok {
+		format = func(t time.Time) string { return t.Format(eventTimeLayout) }
+	}
+
+	units, ok := offsetUnits[locale]
+	if !ok {
+		units = offsetUnits[DefaultLocale]
+	}
+
+	return map[string]any{
+		"formatTime": func(value any, zone string) (string, error) {
+			t, err := time.Parse(time.RFC3339, strings.TrimSpace(fmt.Sprint(value)))
+			if err != nil {
+				return "", err
+			}
+			return format(t.In(models.LoadTimeLocation(zone))), nil
+		},
+		// payloads come back from the outbox as JSON, so numbers may be floats
+		"formatOffset": func(value any) (string, error) {
+			minutes, err := strconv.ParseFloat(fmt.Sprint(value), 64)
+			if err != nil {
+				return "", err
+			}
+			return formatOffset(units, int(minutes)), nil
+		},
+	}
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pick-cee/events-api/internal/config"
+)
+
+// Notification is a single message to one recipient.
+type Notification struct {
+	ID        string // idempotency key, stable across retries
+	Type      string
+	Recipient string
+	Locale    string
+	Payload   map[string]any
+}
+
+// Notifier delivers notifications through one provider.
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// NewNotifier builds the notifier selected by cfg.NotificationProvider.
+func NewNotifier(cfg *config.Config) (Notifier, error) {
+	switch cfg.NotificationProvider {
+	case "novu":
+		if cfg.NovuSecretKey == "" {
+			return nil, fmt.Errorf("NOVU_SECRET_KEY is required for the novu notification provider")
+		}
+		return NewNovuNotifier(cfg.NovuSecretKey), nil
+	case "smtp":
+		if cfg.SMTPHost == "" || cfg.SMTPFrom == "" {
+			return nil, fmt.Errorf("SMTP_HOST and SMTP_FROM are required for the smtp notification provider")
+		}
+		return NewSMTPNotifier(cfg), nil
+	case "file":
+		return NewFileNotifier(cfg.NotificationFile)
+	default:
+		return nil, fmt.Errorf("unknown notification provider %q", cfg.NotificationProvider)
+	}
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	novugo "github.com/novuhq/novu-go"
+	"github.com/novuhq/novu-go/models/components"
+)
+
+// workflowIDs maps each notification type to its Novu workflow.
+var workflowIDs = map[string]string{
+	NotificationWelcome:                "golang-welcome-email",
+	NotificationRegistrationConfirmed:  "event-registration-success-email",
+	NotificationRegistrationCancelled:  "golang-event-registration-cancellation-email",
+	NotificationEventReminder24h:       "golang-event-24h-reminder",
+	NotificationEventReminder1h:        "golang-event-1h-reminder",
+	NotificationEventReminder:          "golang-event-reminder",
+	NotificationWaitlistPromotion:      "golang-event-waitlist-promotion-email",
+	NotificationSeriesRegistration:     "golang-series-registration-success-email",
+	NotificationPasswordReset:          "golang-password-reset-email",
+	NotificationEmailVerification:      "golang-email-verification-email",
+	NotificationCollaboratorInvitation: "golang-event-collaborator-invitation-email",
+	NotificationEventInvitation:        "golang-event-invitation-email",
+	NotificationRegistrationApproved:   "golang-event-registration-approved-email",
+	NotificationRegistrationRejected:   "golang-event-registration-rejected-email",
+	NotificationEventCancelled:         "golang-event-cancelled-email",
+	NotificationEventRescheduled:       "golang-event-rescheduled-email",
+}
+
+// NovuNotifier triggers a Novu workflow per notification; the message content
+// lives in the workflow, which picks its translation from the subscriber's
+// locale.
+type NovuNotifier struct {
+	client *novugo.Novu
+}
+
+func NewNovuNotifier(secretKey string) *NovuNotifier {
+	return &NovuNotifier{
+		client: novugo.New(novugo.WithSecurity(secretKey)),
+	}
+}
+
+func (n *NovuNotifier) Notify(ctx context.Context, notification Notification) error {
+	workflowID, ok := workflowIDs[notification.Type]
+	if !ok {
+		return fmt.Errorf("unknown notification type %q", notification.Type)
+	}
+
+	email := notification.Recipient
+	locale := notification.Locale
+	transactionID := notification.ID
+
+	_, err := n.client.Trigger(ctx, components.TriggerEventRequestDto{
+		WorkflowID:    workflowID,
+		Payload:       notification.Payload,
+		TransactionID: &transactionID,
+		To: components.CreateToSubscriberPayloadDto(components.SubscriberPayloadDto{
+			Email:        &email,
+			Locale:       &locale,
+			SubscriberID: email,
+		}),
+	}, nil)
+
+	return err
+}
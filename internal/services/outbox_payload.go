@@ -0,0 +1,118 @@
+package services
+
+import (
+	"errors"
+	"maps"
+
+	"github.com/pick-cee/events-api/internal/utils"
+)
+
+// sealedPayloadKey holds the payload values that must not be readable at
+// rest, such as links carrying a one-time token. They are encrypted when
+// queued, decrypted into the payload under their own names on delivery, and
+// scrubbed once the message is sent or dead.
+const sealedPayloadKey = "sealed"
+
+// plaintextSecretKeys are the links older messages stored unsealed.
+var plaintextSecretKeys = []string{"resetUrl", "verifyUrl"}
+
+// ErrPayloadScrubbed is returned when delivering a message whose sealed
+// values were already scrubbed; it can never be delivered again.
+var ErrPayloadScrubbed = errors.New("sealed payload values were scrubbed")
+
+// withSealed adds an encrypted value to a payload.
+func (s *EmailService) withSealed(payload map[string]any, name, value string) (map[string]any, error) {
+	sealed, err := utils.Seal(s.cfg.JWTSecret, value)
+	if err != nil {
+		return nil, err
+	}
+
+	values, _ := payload[sealedPayloadKey].(map[string]any)
+	if values == nil {
+		values = map[string]any{}
+	}
+	values[name] = sealed
+	payload[sealedPayloadKey] = values
+	return payload, nil
+}
+
+// openPayload returns a copy of a payload with its sealed values decrypted
+// under their own names, as templates and providers expect them.
+func (s *EmailService) openPayload(payload map[string]any) (map[string]any, error) {
+	values, ok := payload[sealedPayloadKey].(map[string]any)
+	if !ok {
+		return payload, nil
+	}
+
+	opened := maps.Clone(payload)
+	delete(opened, sealedPayloadKey)
+	for name, value := range values {
+		sealed, _ := value.(string)
+		if sealed == "" {
+			return nil, ErrPayloadScrubbed
+		}
+
+		plain, err := utils.Unseal(s.cfg.JWTSecret, sealed)
+		if err != nil {
+			return nil, err
+		}
+		opened[name] = plain
+	}
+	return opened, nil
+}
+
+// ScrubPayload empties the sealed values of a message that was sent or gave
+// up, keeping their names so a replay fails instead of sending a broken
+// link. Links older messages held in plaintext are scrubbed the same way.
+func ScrubPayload(payload map[string]any) {
+	values, _ := payload[sealedPayloadKey].(map[string]any)
+	if values == nil {
+		values = map[string]any{}
+	}
+	for name := range values {
+		values[name] = ""
+	}
+
+	for _, name := range plaintextSecretKeys {
+		if _, ok := payload[name]; ok {
+			delete(payload, name)
+			values[name] = ""
+		}
+	}
+
+	if len(values) > 0 {
+		payload[sealedPayloadKey] = values
+	}
+}
+
+// HasScrubbedPayload reports whether a message lost its sealed values.
+func HasScrubbedPayload(payload map[string]any) bool {
+	values, _ := payload[sealedPayloadKey].(map[string]any)
+	for _, value := range values {
+		if value == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactPayload returns a copy of a payload fit for display, without its
+// sealed values or plaintext links.
+func RedactPayload(payload map[string]any) map[string]any {
+	redacted := maps.Clone(payload)
+
+	if values, ok := payload[sealedPayloadKey].(map[string]any); ok {
+		names := map[string]any{}
+		for name := range values {
+			names[name] = "[redacted]"
+		}
+		redacted[sealedPayloadKey] = names
+	}
+
+	for _, name := range plaintextSecretKeys {
+		if _, ok := redacted[name]; ok {
+			redacted[name] = "[redacted]"
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pick-cee/events-api/internal/models"
+	"github.com/pick-cee/events-api/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// mandatoryNotifications are sent regardless of preferences because the user
+// asked for them or needs them to use the account.
+var mandatoryNotifications = []string{
+	NotificationPasswordReset,
+	NotificationEmailVerification,
+}
+
+func IsMandatoryNotification(notificationType string) bool {
+	return slices.Contains(mandatoryNotifications, notificationType)
+}
+
+// OptionalNotificationTypes lists the notification types users can turn off,
+// sorted.
+func OptionalNotificationTypes() []string {
+	var types []string
+	for _, notificationType := range NotificationTypes() {
+		if !IsMandatoryNotification(notificationType) {
+			types = append(types, notificationType)
+		}
+	}
+	return types
+}
+
+// WantsEmail reports whether the user wants the notification type by email.
+// Mandatory types are always wanted.
+func WantsEmail(tx *gorm.DB, userID uint, notificationType string) (bool, error) {
+	optedOut, err := OptedOutUsers(tx, notificationType, []uint{userID})
+	return !optedOut[userID], err
+}
+
+// OptedOutUsers returns which of the users turned the notification type off
+// for email, either directly or through the "all" preference.
+func OptedOutUsers(tx *gorm.DB, notificationType string, userIDs []uint) (map[uint]bool, error) {
+	optedOut := map[uint]bool{}
+	if len(userIDs) == 0 || IsMandatoryNotification(notificationType) {
+		return optedOut, nil
+	}
+
+	var ids []uint
+	err := tx.Model(&models.NotificationPreference{}).
+		Where("user_id IN ? AND type IN ? AND channel = ? AND enabled = ?",
+			userIDs, []string{notificationType, models.NotificationTypeAll}, models.NotificationChannelEmail, false).
+		Distinct().Pluck("user_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		optedOut[id] = true
+	}
+	return optedOut, nil
+}
+
+// SetNotificationPreference turns a notification type on or off for a user.
+func SetNotificationPreference(tx *gorm.DB, userID uint, notificationType, channel string, enabled bool) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}, {Name: "channel"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "updated_at"}),
+	}).Create(&models.NotificationPreference{
+		UserID:  userID,
+		Type:    notificationType,
+		Channel: channel,
+		Enabled: enabled,
+	}).Error
+}
+
+// UnsubscribeToken signs a one-click unsubscribe link for a user, type and
+// channel. It does not expire, so links in old emails keep working.
+func UnsubscribeToken(secret string, userID uint, notificationType, channel string) string {
+	return utils.SignToken(secret, fmt.Sprintf("unsubscribe:%d:%s:%s", userID, notificationType, channel))
+}
+
+// ParseUnsubscribeToken verifies a token created by UnsubscribeToken.
+func ParseUnsubscribeToken(secret, token string) (userID uint, notificationType, channel string, ok bool) {
+	data, ok := utils.VerifySignedToken(secret, token)
+	if !ok {
+		return 0, "", "", false
+	}
+
+	parts := strings.Split(data, ":")
+	if len(parts) != 4 || parts[0] != "unsubscribe" {
+		return 0, "", "", false
+	}
+
+	id, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return 0, "", "", false
+	}
+	return uint(id), parts[2], parts[3], true
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"github.com/pick-cee/events-api/internal/models"
+	"gorm.io/gorm"
+)
+
+// SyncReminderDelivery copies the outcome of a reminder's outbox message onto
+// its ledger entry: sent, or failed once the outbox gave up on it. Other
+// notification types are ignored.
+func SyncReminderDelivery(tx *gorm.DB, message *models.OutboxMessage) error {
+	if !IsReminderNotification(message.Type) {
+		return nil
+	}
+
+	var updates map[string]any
+	switch message.Status {
+	case models.OutboxStatusSent:
+		updates = map[string]any{"status": models.ReminderStatusSent, "sent_at": message.SentAt, "last_error": ""}
+	case models.OutboxStatusDead:
+		updates = map[string]any{"status": models.ReminderStatusFailed, "last_error": message.LastError}
+	default:
+		return nil
+	}
+
+	return tx.Model(&models.ReminderDelivery{}).Where("outbox_message_id = ?", message.ID).Updates(updates).Error
+}
+
+// RequeueReminderDeliveries marks the failed ledger entries of replayed
+// outbox messages as queued again, so the reminder job does not queue a
+// second copy. messageIDs is a list of IDs or a subquery selecting them.
+func RequeueReminderDeliveries(tx *gorm.DB, messageIDs any) error {
+	return tx.Model(&models.ReminderDelivery{}).
+		Where("outbox_message_id IN (?) AND status = ?", messageIDs, models.ReminderStatusFailed).
+		Update("status", models.ReminderStatusQueued).Error
+}
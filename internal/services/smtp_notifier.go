@@ -0,0 +1,217 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"time"
+
+	"github.com/pick-cee/events-api/internal/config"
+)
+
+// SMTPNotifier renders notifications from the local templates and sends them
+// through an SMTP server, upgrading to TLS when the server offers STARTTLS.
+type SMTPNotifier struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+}
+
+func NewSMTPNotifier(cfg *config.Config) *SMTPNotifier {
+	return &SMTPNotifier{
+		addr:     net.JoinHostPort(cfg.SMTPHost, cfg.SMTPPort),
+		host:     cfg.SMTPHost,
+		username: cfg.SMTPUsername,
+		password: cfg.SMTPPassword,
+		from:     cfg.SMTPFrom,
+	}
+}
+
+func (n *SMTPNotifier) Notify(ctx context.Context, notification Notification) error {
+	message, err := RenderNotification(notification)
+	if err != nil {
+		return err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", n.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.host}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+
+	if n.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.username, n.password, n.host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(n.from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(notification.Recipient); err != nil {
+		return err
+	}
+
+	data, err := n.buildMessage(notification, message)
+	if err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// buildMessage assembles a multipart/alternative message carrying the text
+// and HTML bodies, wrapped in a multipart/mixed one when the notification has
+// attachments.
+func (n *SMTPNotifier) buildMessage(notification Notification, message RenderedMessage) ([]byte, error) {
+	body, contentType, err := alternativeBody(message)
+	if err != nil {
+		return nil, err
+	}
+
+	if attachments := NotificationAttachments(notification.Payload); len(attachments) > 0 {
+		if body, contentType, err = mixedBody(body, contentType, attachments); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", n.from)
+	fmt.Fprintf(&buf, "To: %s\r\n", notification.Recipient)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", notification.ID, n.host)
+	fmt.Fprintf(&buf, "Content-Language: %s\r\n", message.Locale)
+	fmt.Fprintf(&buf, "X-Template: %s/%s/v%s\r\n", notification.Type, message.Locale, message.Version)
+	if unsubscribeURL, ok := notification.Payload["unsubscribeUrl"].(string); ok {
+		// RFC 8058 one-click unsubscribe
+		fmt.Fprintf(&buf, "List-Unsubscribe: <%s>\r\n", unsubscribeURL)
+		buf.WriteString("List-Unsubscribe-Post: List-Unsubscribe=One-Click\r\n")
+	}
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: %s\r\n", contentType)
+	buf.WriteString("\r\n")
+	buf.Write(body)
+	return buf.Bytes(), nil
+}
+
+// alternativeBody encodes the text and HTML bodies as multipart/alternative
+// and returns them with their content type.
+func alternativeBody(message RenderedMessage) ([]byte, string, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", message.Text},
+		{"text/html; charset=utf-8", message.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, "", err
+		}
+
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, "", err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := parts.Close(); err != nil {
+		return nil, "", err
+	}
+
+	return body.Bytes(), fmt.Sprintf("multipart/alternative; boundary=%q", parts.Boundary()), nil
+}
+
+// mixedBody wraps a message body and its attachments, base64-encoded, in a
+// multipart/mixed body.
+func mixedBody(content []byte, contentType string, attachments []Attachment) ([]byte, string, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+
+	w, err := parts.CreatePart(textproto.MIMEHeader{"Content-Type": {contentType}})
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := w.Write(content); err != nil {
+		return nil, "", err
+	}
+
+	for _, attachment := range attachments {
+		attachmentType := attachment.MIME
+		if attachmentType == "" {
+			attachmentType = "application/octet-stream"
+		}
+
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {fmt.Sprintf("%s; name=%q", attachmentType, attachment.Name)},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, "", err
+		}
+
+		// base64 lines are limited to 76 characters
+		encoded := base64.StdEncoding.EncodeToString(attachment.Content)
+		for len(encoded) > 76 {
+			if _, err := io.WriteString(w, encoded[:76]+"\r\n"); err != nil {
+				return nil, "", err
+			}
+			encoded = encoded[76:]
+		}
+		if _, err := io.WriteString(w, encoded+"\r\n"); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := parts.Close(); err != nil {
+		return nil, "", err
+	}
+
+	return body.Bytes(), fmt.Sprintf("multipart/mixed; boundary=%q", parts.Boundary()), nil
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/pick-cee/events-api/internal/models"
+)
+
+// samplePayloads builds example payloads for previewing each notification
+// type, shaped like the ones the Send methods queue.
+var samplePayloads = map[string]func() map[string]any{
+	NotificationWelcome: func() map[string]any {
+		return map[string]any{"name": "Ada Lovelace"}
+	},
+	NotificationRegistrationConfirmed: func() map[string]any {
+		event := sampleEvent()
+		return withEventTimes(event, map[string]any{"name": "Ada Lovelace", "eventTitle": event.Title, "eventLocation": event.Location})
+	},
+	NotificationRegistrationCancelled: func() map[string]any {
+		event := sampleEvent()
+		return withEventTimes(event, map[string]any{"name": "Ada Lovelace", "eventTitle": event.Title})
+	},
+	NotificationEventReminder24h: func() map[string]any {
+		event := sampleEvent()
+		return withEventTimes(event, map[string]any{"name": "Ada Lovelace", "eventTitle": event.Title, "eventLocation": event.Location, "eventDescription": event.Description})
+	},
+	NotificationEventReminder1h: func() map[string]any {
+		event := sampleEvent()
+		return withEventTimes(event, map[string]any{"name": "Ada Lovelace", "eventTitle": event.Title, "eventLocation": event.Location, "eventDescription": event.Description})
+	},
+	NotificationEventReminder: func() map[string]any {
+		event := sampleEvent()
+		return withEventTimes(event, map[string]any{"name": "Ada Lovelace", "eventTitle": event.Title, "eventLocation": event.Location, "eventDescription": event.Description, "reminderOffsetMinutes": 7 * 24 * 60})
+	},
+	NotificationWaitlistPromotion: func() map[string]any {
+		event := sampleEvent()
+		return withEventTimes(event, map[string]any{"name": "Ada Lovelace", "eventTitle": event.Title, "eventLocation": event.Location})
+	},
+	NotificationRegistrationApproved: func() map[string]any {
+		event := sampleEvent()
+		return withEventTimes(event, map[string]any{"name": "Ada Lovelace", "eventTitle": event.Title, "eventLocation": event.Location, "waitlisted": false, "message": "Looking forward to seeing you there!"})
+	},
+	NotificationRegistrationRejected: func() map[string]any {
+		event := sampleEvent()
+		return withEventTimes(event, map[string]any{"name": "Ada Lovelace", "eventTitle": event.Title, "eventLocation": event.Location, "message": "This session is reserved for speakers."})
+	},
+	NotificationEventCancelled: func() map[string]any {
+		event := sampleEvent()
+		return withEventTimes(event, map[string]any{"name": "Ada Lovelace", "eventTitle": event.Title, "eventLocation": event.Location, "reason": "The venue is closed for repairs."})
+	},
+	NotificationEventRescheduled: func() map[string]any {
+		event := sampleEvent()
+		previous := event.DateTime.Add(-24 * time.Hour)
+		return withEventTimes(event, map[string]any{
+			"name":                  "Ada Lovelace",
+			"eventTitle":            event.Title,
+			"eventLocation":         event.Location,
+			"timeChanged":           true,
+			"locationChanged":       false,
+			"previousEventTime":     previous.In(event.TimeLocation()).Format(eventTimeLayout),
+			"previousEventTimeZone": event.TimeZone,
+			"previousEventTimeUTC":  previous.UTC().Format(time.RFC3339),
+			"previousEventLocation": event.Location,
+		})
+	},
+	NotificationSeriesRegistration: func() map[string]any {
+		event := sampleEvent()
+		return map[string]any{
+			"name":               "Ada Lovelace",
+			"seriesTitle":        "Weekly Go Study Group",
+			"seriesLocation":     event.Location,
+			"seriesStartTime":    event.LocalStart().Format(eventTimeLayout),
+			"seriesStartTimeUTC": event.DateTime.UTC().Format(time.RFC3339),
+			"seriesTimeZone":     event.TimeZone,
+			"occurrences":        8,
+		}
+	},
+	NotificationPasswordReset: func() map[string]any {
+		return map[string]any{"name": "Ada Lovelace", "resetUrl": "https://example.com/reset-password?token=sample", "expiresAt": time.Now().Add(time.Hour).UTC().Format(time.RFC3339)}
+	},
+	NotificationEmailVerification: func() map[string]any {
+		return map[string]any{"name": "Ada Lovelace", "verifyUrl": "https://example.com/verify-email?token=sample", "expiresAt": time.Now().Add(48 * time.Hour).UTC().Format(time.RFC3339)}
+	},
+	NotificationCollaboratorInvitation: func() map[string]any {
+		event := sampleEvent()
+		return withEventTimes(event, map[string]any{"inviterName": "Grace Hopper", "eventTitle": event.Title, "eventLocation": event.Location, "permission": models.CollaboratorPermissionEdit, "acceptUrl": "https://example.com/collaborations/1"})
+	},
+	NotificationEventInvitation: func() map[string]any {
+		event := sampleEvent()
+		return withEventTimes(event, map[string]any{"inviterName": "Grace Hopper", "eventTitle": event.Title, "eventLocation": event.Location, "canRegister": true, "inviteUrl": "https://example.com/events/1?invite=sample"})
+	},
+}
+
+// NotificationTypes lists every notification type, sorted.
+func NotificationTypes() []string {
+	return slices.Sorted(maps.Keys(samplePayloads))
+}
+
+// SamplePayload returns an example payload for the notification type.
+func SamplePayload(notificationType string) (map[string]any, bool) {
+	build, ok := samplePayloads[notificationType]
+	if !ok {
+		return nil, false
+	}
+
+	payload := build()
+	payload["unsubscribeUrl"] = "https://api.example.com/api/v1/notifications/unsubscribe?token=sample"
+	payload["preferencesUrl"] = "https://example.com/settings/notifications"
+	return payload, true
+}
+
+func sampleEvent() *models.Event {
+	start := time.Now().Add(7 * 24 * time.Hour).Truncate(time.Hour)
+	return &models.Event{
+		Title:       "GopherCon Africa",
+		Description: "A day of talks and workshops about Go.",
+		Location:    "Landmark Centre, Lagos",
+		DateTime:    start,
+		EndTime:     start.Add(8 * time.Hour),
+		TimeZone:    "Africa/Lagos",
+	}
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+)
+
+// DefaultLocale is used when a notification has no template in the
+// recipient's locale.
+const DefaultLocale = "en"
+
+// templateFS holds the notification templates, one directory per locale:
+//
+//	templates/layout.html.tmpl    shared HTML frame, renders "content" and "footer"
+//	templates/<locale>/<type>.txt.tmpl   defines "version", "subject" and "text"
+//	templates/<locale>/<type>.html.tmpl  defines "content"
+//	templates/<locale>/_footer.txt.tmpl  defines "footer", appended to "text"
+//	templates/<locale>/_footer.html.tmpl defines "footer"
+//
+// A locale without footers uses the English ones.
+//
+//go:embed all:templates
+var templateFS embed.FS
+
+type notificationTemplate struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// notificationTemplates maps locale and notification type to its templates.
+var notificationTemplates = mustLoadTemplates()
+
+// RenderedMessage is a notification rendered for providers that send the
+// content themselves.
+type RenderedMessage struct {
+	Locale  string `json:"locale"`  // locale of the template that was used
+	Version string `json:"version"` // version declared by the template
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html"`
+}
+
+// RenderNotification renders the subject, text and HTML bodies in the
+// notification's locale, falling back to its base language and then English.
+func RenderNotification(notification Notification) (RenderedMessage, error) {
+	locale, tmpl, ok := lookupTemplate(notification.Locale, notification.Type)
+	if !ok {
+		return RenderedMessage{}, fmt.Errorf("no template for notification type %q", notification.Type)
+	}
+
+	version, err := executeText(tmpl.text, "version", notification.Payload)
+	if err != nil {
+		return RenderedMessage{}, err
+	}
+
+	subject, err := executeText(tmpl.text, "subject", notification.Payload)
+	if err != nil {
+		return RenderedMessage{}, err
+	}
+
+	text, err := executeText(tmpl.text, "text", notification.Payload)
+	if err != nil {
+		return RenderedMessage{}, err
+	}
+
+	footer, err := executeText(tmpl.text, "footer", notification.Payload)
+	if err != nil {
+		return RenderedMessage{}, err
+	}
+
+	var html bytes.Buffer
+	if err := tmpl.html.ExecuteTemplate(&html, "layout", map[string]any{
+		"Subject": strings.TrimSpace(subject),
+		"Locale":  locale,
+		"Data":    notification.Payload,
+	}); err != nil {
+		return RenderedMessage{}, fmt.Errorf("failed to render html: %w", err)
+	}
+
+	return RenderedMessage{
+		Locale:  locale,
+		Version: strings.TrimSpace(version),
+		Subject: strings.TrimSpace(subject),
+		Text:    strings.TrimSpace(strings.TrimSpace(text)+"\n\n"+strings.TrimSpace(footer)) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
+
+// NormalizeLocale maps a language tag such as "fr-CA" or "FR" to a supported
+// locale. It returns "" when neither the tag nor its base language is
+// supported.
+func NormalizeLocale(tag string) string {
+	tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	if _, ok := notificationTemplates[tag]; ok {
+		return tag
+	}
+
+	base, _, _ := strings.Cut(tag, "-")
+	if _, ok := notificationTemplates[base]; ok {
+		return base
+	}
+	return ""
+}
+
+// SupportedLocales lists the locales that have templates, sorted.
+func SupportedLocales() []string {
+	locales := make([]string, 0, len(notificationTemplates))
+	for locale := range notificationTemplates {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// TemplateLocales lists the locales with a template for the notification
+// type, sorted.
+func TemplateLocales(notificationType string) []string {
+	var locales []string
+	for _, locale := range SupportedLocales() {
+		if _, ok := notificationTemplates[locale][notificationType]; ok {
+			locales = append(locales, locale)
+		}
+	}
+	return locales
+}
+
+func lookupTemplate(locale, notificationType string) (string, *notificationTemplate, bool) {
+	candidates := []string{DefaultLocale}
+	if normalized := NormalizeLocale(locale); normalized != "" {
+		candidates = []string{normalized, DefaultLocale}
+	}
+
+	for _, candidate := range candidates {
+		if tmpl, ok := notificationTemplates[candidate][notificationType]; ok {
+			return candidate, tmpl, true
+		}
+	}
+	return "", nil, false
+}
+
+func executeText(tmpl *texttemplate.Template, name string, payload map[string]any) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, name, payload); err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+func mustLoadTemplates() map[string]map[string]*notificationTemplate {
+	layout := htmltemplate.Must(htmltemplate.New("").Option("missingkey=error").Funcs(templateFuncs(DefaultLocale)).ParseFS(templateFS, "templates/layout.html.tmpl"))
+
+	textFiles, err := fs.Glob(templateFS, "templates/*/*.txt.tmpl")
+	if err != nil {
+		panic(err)
+	}
+
+	loaded := map[string]map[string]*notificationTemplate{}
+	for _, textFile := range textFiles {
+		if strings.HasPrefix(path.Base(textFile), "_") {
+			continue
+		}
+
+		dir := path.Dir(textFile)
+		locale := path.Base(dir)
+		notificationType := strings.TrimSuffix(path.Base(textFile), ".txt.tmpl")
+		htmlFile := path.Join(dir, notificationType+".html.tmpl")
+
+		footerDir := dir
+		if _, err := fs.Stat(templateFS, path.Join(dir, "_footer.txt.tmpl")); err != nil {
+			footerDir = path.Join("templates", DefaultLocale)
+		}
+
+		funcs := templateFuncs(locale)
+		html := htmltemplate.Must(htmltemplate.Must(layout.Clone()).Funcs(funcs).ParseFS(templateFS, htmlFile, path.Join(footerDir, "_footer.html.tmpl")))
+		text := texttemplate.Must(texttemplate.New("").Option("missingkey=error").Funcs(funcs).ParseFS(templateFS, textFile, path.Join(footerDir, "_footer.txt.tmpl")))
+
+		if loaded[locale] == nil {
+			loaded[locale] = map[string]*notificationTemplate{}
+		}
+		loaded[locale][notificationType] = &notificationTemplate{text: text, html: html}
+	}
+
+	if len(loaded[DefaultLocale]) == 0 {
+		panic("no " + DefaultLocale + " notification templates")
+	}
+	return loaded
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// GenerateRandomToken returns a URL-safe random string built from n bytes.
+func GenerateRandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the hex SHA-256 of a token so it can be stored and looked
+// up without keeping the token itself.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// SignToken returns data followed by an HMAC-SHA256 signature, both
+// base64url-encoded and joined by a dot.
+func SignToken(secret, data string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(data)) + "." + signature(secret, data)
+}
+
+// VerifySignedToken returns the data of a token created by SignToken, or
+// false when the token is malformed or its signature does not match.
+func VerifySignedToken(secret, token string) (string, bool) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", false
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", false
+	}
+
+	if !hmac.Equal([]byte(sig), []byte(signature(secret, string(data)))) {
+		return "", false
+	}
+	return string(data), true
+}
+
+// Seal encrypts data with AES-256-GCM under a key derived from secret, for
+// values that must be read back later but not be readable at rest.
+func Seal(secret, data string) (string, error) {
+	aead, err := sealingCipher(secret)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(aead.Seal(nonce, nonce, []byte(data), nil)), nil
+}
+
+// Unseal decrypts a value created by Seal.
+func Unseal(secret, sealed string) (string, error) {
+	aead, err := sealingCipher(secret)
+	if err != nil {
+		return "", err
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(sealed)
+	if err != nil || len(raw) < aead.NonceSize() {
+		return "", errors.New("malformed sealed value")
+	}
+
+	data, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func sealingCipher(secret string) (cipher.AEAD, error) {
+	// a key of its own, so sealed values and signatures never share one
+	key := sha256.Sum256([]byte("seal:" + secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func signature(secret, data string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(data))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}